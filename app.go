@@ -2,24 +2,30 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"htpatcher/internal/domain"
 	"htpatcher/internal/repository"
 	"htpatcher/internal/service"
+	"htpatcher/internal/translator"
+	"io"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
 type App struct {
-	ctx               context.Context
-	gameService       *service.GameService
-	patchService      *service.PatchService
-	backupService     *service.BackupService
-	collectionService *service.CollectionService
-	downloadService   *service.DownloadService
-	updateService     *service.UpdateService
-	exportService     *service.ExportService
-	justUpdated       bool
+	ctx                context.Context
+	gameService        *service.GameService
+	patchService       *service.PatchService
+	backupService      *service.BackupService
+	collectionService  *service.CollectionService
+	downloadService    *service.DownloadService
+	updateService      *service.UpdateService
+	exportService      *service.ExportService
+	signatureService   *service.SignatureService
+	translationService *service.TranslationService
+	justUpdated        bool
 }
 
 // NewApp creates a new App application struct
@@ -46,11 +52,14 @@ func (a *App) startup(ctx context.Context) {
 
 	// Initialize services
 	a.gameService = service.NewGameService(logger)
-	a.patchService = service.NewPatchService(patchRepo, logger)
-	a.backupService = service.NewBackupService(logger)
+	settings := &settingsProvider{app: a}
+	a.signatureService = service.NewSignatureService(patchRepo, settings, logger)
+	a.patchService = service.NewPatchService(patchRepo, a.signatureService, settings, logger)
+	a.backupService = service.NewBackupService(&backupLocator{app: a}, logger)
 	a.downloadService = service.NewDownloadService(patchRepo, logger)
 	a.updateService = service.NewUpdateService(logger)
 	a.exportService = service.NewExportService(logger)
+	a.translationService = service.NewTranslationService(patchRepo, logger)
 
 	collectionService, err := service.NewCollectionService(storageRepo)
 	if err != nil {
@@ -93,6 +102,44 @@ func (l *AppLogger) Warn(message string) {
 	})
 }
 
+// backupLocator resolves backup locations from the games collection
+type backupLocator struct {
+	app *App
+}
+
+func (l *backupLocator) GetBackupSettings() domain.BackupSettings {
+	if l.app.collectionService == nil {
+		return domain.BackupSettings{Mode: domain.BackupModeFolder}
+	}
+	return l.app.collectionService.GetBackupSettings()
+}
+
+func (l *backupLocator) FindGameByDir(gameDir string) (*domain.LocatedGame, error) {
+	if l.app.collectionService == nil {
+		return nil, errors.New("collection not loaded")
+	}
+	return l.app.collectionService.FindGameByDir(gameDir)
+}
+
+// settingsProvider provides the signature and override settings from the games collection
+type settingsProvider struct {
+	app *App
+}
+
+func (p *settingsProvider) GetSignatureSettings() domain.SignatureSettings {
+	if p.app.collectionService == nil {
+		return domain.SignatureSettings{Policy: domain.SignaturePolicyWarn}
+	}
+	return p.app.collectionService.GetSignatureSettings()
+}
+
+func (p *settingsProvider) GetOverrideSettings() domain.OverrideSettings {
+	if p.app.collectionService == nil {
+		return domain.OverrideSettings{}
+	}
+	return p.app.collectionService.GetOverrideSettings()
+}
+
 // LogMessage represents a log message sent to the frontend
 type LogMessage struct {
 	Message string `json:"message"`
@@ -159,9 +206,21 @@ func (a *App) FetchAllPatches() ([]domain.PatchEntry, error) {
 
 // ApplyPatch applies a patch to a game
 func (a *App) ApplyPatch(gameInfo domain.GameInfo, patchInfo domain.PatchInfo, launchAfterPatch bool, backupBeforePatch bool) error {
+	// The patch info comes back from the frontend, back up and apply only what the verified patch file holds
+	if err := a.patchService.ReloadPatchInfo(&patchInfo); err != nil {
+		return err
+	}
+
+	// Refuse unsafe overrides before the backup copies anything
+	if err := a.patchService.CheckOverrides(&gameInfo, &patchInfo); err != nil {
+		return err
+	}
+
+	var backupManifest *domain.BackupManifest
 	if backupBeforePatch {
 		a.Log("Backing up game data...")
-		err := a.backupService.BackupGameData(&gameInfo, &patchInfo)
+		var err error
+		backupManifest, err = a.backupService.BackupGameData(&gameInfo, &patchInfo)
 		if err != nil {
 			a.LogError("Failed to backup game data")
 			return err
@@ -173,6 +232,14 @@ func (a *App) ApplyPatch(gameInfo domain.GameInfo, patchInfo domain.PatchInfo, l
 		return err
 	}
 
+	if backupManifest != nil {
+		err = a.backupService.RecordPatchedFiles(&gameInfo, backupManifest.Generation)
+		if err != nil {
+			a.LogError("Failed to record patched files in backup")
+			return err
+		}
+	}
+
 	if launchAfterPatch {
 		a.Log("Launching game...")
 		err = a.gameService.LaunchGame(gameInfo.ExePath)
@@ -186,6 +253,11 @@ func (a *App) ApplyPatch(gameInfo domain.GameInfo, patchInfo domain.PatchInfo, l
 	return nil
 }
 
+// InspectPatch describes what a patch contains and will change, without applying it
+func (a *App) InspectPatch(patchPath string) (*domain.PatchInspection, error) {
+	return a.patchService.InspectPatch(patchPath)
+}
+
 // ===== Download Service Methods =====
 
 // DownloadPatch downloads a patch
@@ -215,6 +287,50 @@ func (a *App) RestoreGameBackup(gameInfo domain.GameInfo) error {
 	return nil
 }
 
+// ListGameBackups lists all backup generations of a game
+func (a *App) ListGameBackups(gameInfo domain.GameInfo) ([]domain.BackupManifest, error) {
+	return a.backupService.ListBackups(&gameInfo)
+}
+
+// RestoreGameBackupGeneration restores a game to its state before a backup generation
+func (a *App) RestoreGameBackupGeneration(gameInfo domain.GameInfo, generation int) error {
+	a.Log(fmt.Sprintf("Starting restoration of backup generation %d...", generation))
+	err := a.backupService.RestoreGeneration(&gameInfo, generation)
+	if err != nil {
+		a.LogError("Failed to restore backup")
+		return err
+	}
+	a.LogSuccess("✓ Backup restored successfully!")
+	return nil
+}
+
+// RestoreGameBackupFiles restores individual files of a game to their state before a backup generation
+func (a *App) RestoreGameBackupFiles(gameInfo domain.GameInfo, generation int, files []string) error {
+	a.Log(fmt.Sprintf("Restoring %d files from backup generation %d...", len(files), generation))
+	err := a.backupService.RestoreFiles(&gameInfo, generation, files)
+	if err != nil {
+		a.LogError("Failed to restore files")
+		return err
+	}
+	a.LogSuccess("✓ Files restored successfully!")
+	return nil
+}
+
+// VerifyGameBackup verifies the files stored in a backup generation
+func (a *App) VerifyGameBackup(gameInfo domain.GameInfo, generation int) (*domain.BackupVerification, error) {
+	return a.backupService.VerifyBackup(&gameInfo, generation)
+}
+
+// VerifyPatchedGameFiles verifies that the game files still match what the patch wrote
+func (a *App) VerifyPatchedGameFiles(gameInfo domain.GameInfo) (*domain.BackupVerification, error) {
+	return a.backupService.VerifyPatchedFiles(&gameInfo)
+}
+
+// PruneGameBackups removes backup generations of a game
+func (a *App) PruneGameBackups(gameInfo domain.GameInfo, generations []int) error {
+	return a.backupService.PruneBackups(&gameInfo, generations)
+}
+
 // ===== Collection Service Methods =====
 
 // PrepareGameToAddToCollection prepares a game to be added to the collection
@@ -273,6 +389,51 @@ func (a *App) SetGamesPerRow(count int) error {
 	return a.collectionService.SetGamesPerRow(count)
 }
 
+// GetBackupSettings returns the backup settings
+func (a *App) GetBackupSettings() domain.BackupSettings {
+	return a.collectionService.GetBackupSettings()
+}
+
+// SetBackupSettings sets the backup settings
+func (a *App) SetBackupSettings(settings domain.BackupSettings) error {
+	return a.collectionService.SetBackupSettings(settings)
+}
+
+// SelectBackupRoot opens a dialog to select the folder backup archives are stored in
+func (a *App) SelectBackupRoot() (string, error) {
+	return a.collectionService.SelectBackupRoot(a.ctx)
+}
+
+// GetTranslatorSettings returns the machine translation backend settings
+func (a *App) GetTranslatorSettings() domain.TranslatorSettings {
+	return a.collectionService.GetTranslatorSettings()
+}
+
+// SetTranslatorSettings sets the machine translation backend settings
+func (a *App) SetTranslatorSettings(settings domain.TranslatorSettings) error {
+	return a.collectionService.SetTranslatorSettings(settings)
+}
+
+// GetSignatureSettings returns the patch signature settings
+func (a *App) GetSignatureSettings() domain.SignatureSettings {
+	return a.collectionService.GetSignatureSettings()
+}
+
+// SetSignatureSettings sets the patch signature policy and trusted keys
+func (a *App) SetSignatureSettings(settings domain.SignatureSettings) error {
+	return a.collectionService.SetSignatureSettings(settings)
+}
+
+// GetOverrideSettings returns the folders patches may write override files to
+func (a *App) GetOverrideSettings() domain.OverrideSettings {
+	return a.collectionService.GetOverrideSettings()
+}
+
+// SetOverrideSettings sets the folders patches may write override files to
+func (a *App) SetOverrideSettings(settings domain.OverrideSettings) error {
+	return a.collectionService.SetOverrideSettings(settings)
+}
+
 // SetGamePinned sets the pinned status of a game
 func (a *App) SetGamePinned(id string, pinned bool) error {
 	return a.collectionService.SetGamePinned(id, pinned)
@@ -316,3 +477,67 @@ func (a *App) ApplyUpdate() error {
 func (a *App) ExportPatchedFiles(gameDir string, friendlyName string) error {
 	return a.exportService.ExportPatchedFiles(a.ctx, gameDir, friendlyName)
 }
+
+// ExportDeltaPatch exports patched files as binary deltas against the backed up originals
+func (a *App) ExportDeltaPatch(gameDir string, friendlyName string) error {
+	gameInfo := &domain.GameInfo{GameDir: gameDir}
+	return a.exportService.ExportDeltaPatch(a.ctx, gameDir, friendlyName, func(fn func(relPath string, r io.Reader) error) error {
+		return a.backupService.ReadOriginalFiles(gameInfo, fn)
+	})
+}
+
+// ApplyDeltaPatch applies a delta patch archive to a game
+func (a *App) ApplyDeltaPatch(gameDir string) error {
+	return a.exportService.ApplyDeltaPatch(a.ctx, gameDir)
+}
+
+// ===== Translation Service Methods =====
+
+// ExportPatchTranslations exports the dictionary of a patch to a translation interchange format
+func (a *App) ExportPatchTranslations(exePath string, format string) error {
+	gameInfo, err := a.gameService.GetGameInfoFromExePath(exePath)
+	if err != nil {
+		return err
+	}
+	return a.translationService.ExportTranslations(a.ctx, gameInfo, format, func(fn func(relPath string, r io.Reader) error) error {
+		return a.backupService.ReadOriginalFiles(gameInfo, fn)
+	})
+}
+
+// ImportPatchTranslations imports a translation interchange file into the dictionary of a patch
+func (a *App) ImportPatchTranslations() error {
+	return a.translationService.ImportTranslations(a.ctx)
+}
+
+// PrefillPatchTranslations fills the untranslated texts of a patch with machine translations
+func (a *App) PrefillPatchTranslations(exePath string) error {
+	settings := a.collectionService.GetTranslatorSettings()
+	if settings.Endpoint == "" {
+		return errors.New("no machine translation endpoint configured, set one in the settings")
+	}
+	gameInfo, err := a.gameService.GetGameInfoFromExePath(exePath)
+	if err != nil {
+		return err
+	}
+	backend := translator.NewHTTPTranslator(settings.Endpoint, settings.APIKey)
+	return a.translationService.PrefillTranslations(a.ctx, gameInfo, backend, settings, func(fn func(relPath string, r io.Reader) error) error {
+		return a.backupService.ReadOriginalFiles(gameInfo, fn)
+	})
+}
+
+// ===== Signature Service Methods =====
+
+// GetTrustedKeys returns the publisher keys shipped with the app and the ones added by the user
+func (a *App) GetTrustedKeys() []domain.TrustedKey {
+	return a.signatureService.GetTrustedKeys()
+}
+
+// GenerateSigningKey generates a signing key for a publisher and saves it to a key file
+func (a *App) GenerateSigningKey(publisher string) (*domain.TrustedKey, error) {
+	return a.signatureService.GenerateSigningKey(a.ctx, publisher)
+}
+
+// SignPatch signs a patch with a signing key
+func (a *App) SignPatch() error {
+	return a.signatureService.SignPatch(a.ctx)
+}
@@ -8,3 +8,11 @@ export function getDlsiteImageUrl(code: string): string {
   return `https://img.dlsite.jp/modpub/images2/work/doujin/${folderCode}/${code}_img_main.webp`;
 }
 
+
+export function formatFileSize(bytes: number): string {
+  if (bytes === 0) return "0 Bytes";
+  const k = 1024;
+  const sizes = ["Bytes", "KB", "MB", "GB"];
+  const i = Math.floor(Math.log(bytes) / Math.log(k));
+  return Math.round((bytes / Math.pow(k, i)) * 100) / 100 + " " + sizes[i];
+}
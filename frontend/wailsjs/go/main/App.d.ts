@@ -4,6 +4,8 @@ import {domain} from '../models';
 
 export function AddGameToCollection(arg1:domain.LocatedGame,arg2:string,arg3:string,arg4:Array<string>):Promise<void>;
 
+export function ApplyDeltaPatch(arg1:string):Promise<void>;
+
 export function ApplyPatch(arg1:domain.GameInfo,arg2:domain.PatchInfo,arg3:boolean,arg4:boolean):Promise<void>;
 
 export function ApplyUpdate():Promise<void>;
@@ -16,10 +18,18 @@ export function DownloadPatch(arg1:string):Promise<domain.PatchInfo>;
 
 export function DownloadUpdate(arg1:domain.ReleaseInfo):Promise<void>;
 
+export function ExportDeltaPatch(arg1:string,arg2:string):Promise<void>;
+
+export function ExportPatchTranslations(arg1:string,arg2:string):Promise<void>;
+
 export function ExportPatchedFiles(arg1:string,arg2:string):Promise<void>;
 
 export function FetchAllPatches():Promise<Array<domain.PatchEntry>>;
 
+export function GenerateSigningKey(arg1:string):Promise<domain.TrustedKey>;
+
+export function GetBackupSettings():Promise<domain.BackupSettings>;
+
 export function GetCurrentVersion():Promise<number>;
 
 export function GetGameInfoFromExePath(arg1:string):Promise<domain.GameInfo>;
@@ -30,10 +40,24 @@ export function GetGamesPerRow():Promise<number>;
 
 export function GetLatestReleaseInfo():Promise<domain.ReleaseInfo>;
 
+export function GetOverrideSettings():Promise<domain.OverrideSettings>;
+
 export function GetPersistentDataPath():Promise<string>;
 
+export function GetSignatureSettings():Promise<domain.SignatureSettings>;
+
+export function GetTranslatorSettings():Promise<domain.TranslatorSettings>;
+
+export function GetTrustedKeys():Promise<Array<domain.TrustedKey>>;
+
+export function ImportPatchTranslations():Promise<void>;
+
+export function InspectPatch(arg1:string):Promise<domain.PatchInspection>;
+
 export function LaunchGameFromPath(arg1:string):Promise<void>;
 
+export function ListGameBackups(arg1:domain.GameInfo):Promise<Array<domain.BackupManifest>>;
+
 export function Log(arg1:string):Promise<void>;
 
 export function LogError(arg1:string):Promise<void>;
@@ -42,16 +66,28 @@ export function LogSuccess(arg1:string):Promise<void>;
 
 export function OpenFolder(arg1:string):Promise<void>;
 
+export function PrefillPatchTranslations(arg1:string):Promise<void>;
+
 export function PrepareGameToAddToCollection():Promise<domain.LocatedGame>;
 
+export function PruneGameBackups(arg1:domain.GameInfo,arg2:Array<number>):Promise<void>;
+
 export function RemoveGameFromCollection(arg1:string):Promise<void>;
 
 export function RestoreGameBackup(arg1:domain.GameInfo):Promise<void>;
 
+export function RestoreGameBackupFiles(arg1:domain.GameInfo,arg2:number,arg3:Array<string>):Promise<void>;
+
+export function RestoreGameBackupGeneration(arg1:domain.GameInfo,arg2:number):Promise<void>;
+
+export function SelectBackupRoot():Promise<string>;
+
 export function SelectGameExeFile():Promise<domain.GameInfo>;
 
 export function SelectPatchFile():Promise<domain.PatchInfo>;
 
+export function SetBackupSettings(arg1:domain.BackupSettings):Promise<void>;
+
 export function SetGamePinned(arg1:string,arg2:boolean):Promise<void>;
 
 export function SetGamePlayStatus(arg1:string,arg2:string):Promise<void>;
@@ -60,4 +96,16 @@ export function SetGameTranslated(arg1:string,arg2:boolean):Promise<void>;
 
 export function SetGamesPerRow(arg1:number):Promise<void>;
 
+export function SetOverrideSettings(arg1:domain.OverrideSettings):Promise<void>;
+
+export function SetSignatureSettings(arg1:domain.SignatureSettings):Promise<void>;
+
+export function SetTranslatorSettings(arg1:domain.TranslatorSettings):Promise<void>;
+
+export function SignPatch():Promise<void>;
+
 export function UpdateGameMetadata(arg1:string,arg2:string,arg3:Array<string>):Promise<void>;
+
+export function VerifyGameBackup(arg1:domain.GameInfo,arg2:number):Promise<domain.BackupVerification>;
+
+export function VerifyPatchedGameFiles(arg1:domain.GameInfo):Promise<domain.BackupVerification>;
@@ -6,6 +6,10 @@ export function AddGameToCollection(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['AddGameToCollection'](arg1, arg2, arg3, arg4);
 }
 
+export function ApplyDeltaPatch(arg1) {
+  return window['go']['main']['App']['ApplyDeltaPatch'](arg1);
+}
+
 export function ApplyPatch(arg1, arg2, arg3, arg4) {
   return window['go']['main']['App']['ApplyPatch'](arg1, arg2, arg3, arg4);
 }
@@ -30,6 +34,14 @@ export function DownloadUpdate(arg1) {
   return window['go']['main']['App']['DownloadUpdate'](arg1);
 }
 
+export function ExportDeltaPatch(arg1, arg2) {
+  return window['go']['main']['App']['ExportDeltaPatch'](arg1, arg2);
+}
+
+export function ExportPatchTranslations(arg1, arg2) {
+  return window['go']['main']['App']['ExportPatchTranslations'](arg1, arg2);
+}
+
 export function ExportPatchedFiles(arg1, arg2) {
   return window['go']['main']['App']['ExportPatchedFiles'](arg1, arg2);
 }
@@ -38,6 +50,14 @@ export function FetchAllPatches() {
   return window['go']['main']['App']['FetchAllPatches']();
 }
 
+export function GenerateSigningKey(arg1) {
+  return window['go']['main']['App']['GenerateSigningKey'](arg1);
+}
+
+export function GetBackupSettings() {
+  return window['go']['main']['App']['GetBackupSettings']();
+}
+
 export function GetCurrentVersion() {
   return window['go']['main']['App']['GetCurrentVersion']();
 }
@@ -58,14 +78,42 @@ export function GetLatestReleaseInfo() {
   return window['go']['main']['App']['GetLatestReleaseInfo']();
 }
 
+export function GetOverrideSettings() {
+  return window['go']['main']['App']['GetOverrideSettings']();
+}
+
 export function GetPersistentDataPath() {
   return window['go']['main']['App']['GetPersistentDataPath']();
 }
 
+export function GetSignatureSettings() {
+  return window['go']['main']['App']['GetSignatureSettings']();
+}
+
+export function GetTranslatorSettings() {
+  return window['go']['main']['App']['GetTranslatorSettings']();
+}
+
+export function GetTrustedKeys() {
+  return window['go']['main']['App']['GetTrustedKeys']();
+}
+
+export function ImportPatchTranslations() {
+  return window['go']['main']['App']['ImportPatchTranslations']();
+}
+
+export function InspectPatch(arg1) {
+  return window['go']['main']['App']['InspectPatch'](arg1);
+}
+
 export function LaunchGameFromPath(arg1) {
   return window['go']['main']['App']['LaunchGameFromPath'](arg1);
 }
 
+export function ListGameBackups(arg1) {
+  return window['go']['main']['App']['ListGameBackups'](arg1);
+}
+
 export function Log(arg1) {
   return window['go']['main']['App']['Log'](arg1);
 }
@@ -82,10 +130,18 @@ export function OpenFolder(arg1) {
   return window['go']['main']['App']['OpenFolder'](arg1);
 }
 
+export function PrefillPatchTranslations(arg1) {
+  return window['go']['main']['App']['PrefillPatchTranslations'](arg1);
+}
+
 export function PrepareGameToAddToCollection() {
   return window['go']['main']['App']['PrepareGameToAddToCollection']();
 }
 
+export function PruneGameBackups(arg1, arg2) {
+  return window['go']['main']['App']['PruneGameBackups'](arg1, arg2);
+}
+
 export function RemoveGameFromCollection(arg1) {
   return window['go']['main']['App']['RemoveGameFromCollection'](arg1);
 }
@@ -94,6 +150,18 @@ export function RestoreGameBackup(arg1) {
   return window['go']['main']['App']['RestoreGameBackup'](arg1);
 }
 
+export function RestoreGameBackupFiles(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RestoreGameBackupFiles'](arg1, arg2, arg3);
+}
+
+export function RestoreGameBackupGeneration(arg1, arg2) {
+  return window['go']['main']['App']['RestoreGameBackupGeneration'](arg1, arg2);
+}
+
+export function SelectBackupRoot() {
+  return window['go']['main']['App']['SelectBackupRoot']();
+}
+
 export function SelectGameExeFile() {
   return window['go']['main']['App']['SelectGameExeFile']();
 }
@@ -102,6 +170,10 @@ export function SelectPatchFile() {
   return window['go']['main']['App']['SelectPatchFile']();
 }
 
+export function SetBackupSettings(arg1) {
+  return window['go']['main']['App']['SetBackupSettings'](arg1);
+}
+
 export function SetGamePinned(arg1, arg2) {
   return window['go']['main']['App']['SetGamePinned'](arg1, arg2);
 }
@@ -118,6 +190,30 @@ export function SetGamesPerRow(arg1) {
   return window['go']['main']['App']['SetGamesPerRow'](arg1);
 }
 
+export function SetOverrideSettings(arg1) {
+  return window['go']['main']['App']['SetOverrideSettings'](arg1);
+}
+
+export function SetSignatureSettings(arg1) {
+  return window['go']['main']['App']['SetSignatureSettings'](arg1);
+}
+
+export function SetTranslatorSettings(arg1) {
+  return window['go']['main']['App']['SetTranslatorSettings'](arg1);
+}
+
+export function SignPatch() {
+  return window['go']['main']['App']['SignPatch']();
+}
+
 export function UpdateGameMetadata(arg1, arg2, arg3) {
   return window['go']['main']['App']['UpdateGameMetadata'](arg1, arg2, arg3);
 }
+
+export function VerifyGameBackup(arg1, arg2) {
+  return window['go']['main']['App']['VerifyGameBackup'](arg1, arg2);
+}
+
+export function VerifyPatchedGameFiles(arg1) {
+  return window['go']['main']['App']['VerifyPatchedGameFiles'](arg1);
+}
@@ -104,9 +104,193 @@ export namespace domain {
 		    return a;
 		}
 	}
+	export class BackupFile {
+	    path: string;
+	    sha256: string;
+	    size: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupFile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.sha256 = source["sha256"];
+	        this.size = source["size"];
+	    }
+	}
+	export class BackupPatch {
+	    fileName: string;
+	    sha256: string;
+	    version: number;
+	    locale: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupPatch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.fileName = source["fileName"];
+	        this.sha256 = source["sha256"];
+	        this.version = source["version"];
+	        this.locale = source["locale"];
+	    }
+	}
+	export class BackupManifest {
+	    generation: number;
+	    createdAt: string;
+	    patchedAt: string;
+	    patch: BackupPatch;
+	    files: BackupFile[];
+	    createdFiles: string[];
+	    createdDirs: string[];
+	    patchedFiles: BackupFile[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupManifest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.generation = source["generation"];
+	        this.createdAt = source["createdAt"];
+	        this.patchedAt = source["patchedAt"];
+	        this.patch = this.convertValues(source["patch"], BackupPatch);
+	        this.files = this.convertValues(source["files"], BackupFile);
+	        this.createdFiles = source["createdFiles"];
+	        this.createdDirs = source["createdDirs"];
+	        this.patchedFiles = this.convertValues(source["patchedFiles"], BackupFile);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class BackupSettings {
+	    mode: string;
+	    format: string;
+	    root: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.format = source["format"];
+	        this.root = source["root"];
+	    }
+	}
+	export class BackupVerification {
+	    generation: number;
+	    valid: boolean;
+	    modified: string[];
+	    missing: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BackupVerification(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.generation = source["generation"];
+	        this.valid = source["valid"];
+	        this.modified = source["modified"];
+	        this.missing = source["missing"];
+	    }
+	}
+	export class DataFieldToPatch {
+	    path: string;
+	    type: string;
+	    window?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DataFieldToPatch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.type = source["type"];
+	        this.window = source["window"];
+	    }
+	}
+	export class DataFileToPatch {
+	    file: string;
+	    fields: DataFieldToPatch[];
+	
+	    static createFrom(source: any = {}) {
+	        return new DataFileToPatch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.fields = this.convertValues(source["fields"], DataFieldToPatch);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FontSettings {
+	    mainFontFilename: string;
+	    numberFontFilename: string;
+	    fallbackFonts: string;
+	    fontSize: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FontSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mainFontFilename = source["mainFontFilename"];
+	        this.numberFontFilename = source["numberFontFilename"];
+	        this.fallbackFonts = source["fallbackFonts"];
+	        this.fontSize = source["fontSize"];
+	    }
+	}
 	export class PluginReplaceRule {
 	    match: string;
 	    replace: string;
+	    regex: boolean;
+	    expectedMatches: number;
+	    strict: boolean;
+	    function: string;
+	    file: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new PluginReplaceRule(source);
@@ -116,12 +300,19 @@ export namespace domain {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.match = source["match"];
 	        this.replace = source["replace"];
+	        this.regex = source["regex"];
+	        this.expectedMatches = source["expectedMatches"];
+	        this.strict = source["strict"];
+	        this.function = source["function"];
+	        this.file = source["file"];
 	    }
 	}
 	export class PluginToPatch {
 	    plugin: string;
 	    parametersPatchScript: string;
 	    replaceRules: PluginReplaceRule[];
+	    translateStrings: boolean;
+	    parameterPaths: ParameterPathToPatch[];
 	
 	    static createFrom(source: any = {}) {
 	        return new PluginToPatch(source);
@@ -132,6 +323,8 @@ export namespace domain {
 	        this.plugin = source["plugin"];
 	        this.parametersPatchScript = source["parametersPatchScript"];
 	        this.replaceRules = this.convertValues(source["replaceRules"], PluginReplaceRule);
+	        this.translateStrings = source["translateStrings"];
+	        this.parameterPaths = this.convertValues(source["parameterPaths"], ParameterPathToPatch);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -171,6 +364,7 @@ export namespace domain {
 	    function: string;
 	    rootType: string;
 	    parameterPathsToPatch: ParameterPathToPatch[];
+	    recursive: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new ParameterToPatch(source);
@@ -182,6 +376,7 @@ export namespace domain {
 	        this.function = source["function"];
 	        this.rootType = source["rootType"];
 	        this.parameterPathsToPatch = this.convertValues(source["parameterPathsToPatch"], ParameterPathToPatch);
+	        this.recursive = source["recursive"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -211,6 +406,12 @@ export namespace domain {
 	    creditsLocation: string;
 	    dynamicWrapWidth: boolean;
 	    locale: string;
+	    commandsPatchScript: string;
+	    commandCodesToPatch: number[];
+	    engine: string;
+	    fonts?: FontSettings;
+	    dataFilesToPatch: string[];
+	    dataFieldsToPatch: DataFileToPatch[];
 	
 	    static createFrom(source: any = {}) {
 	        return new Config(source);
@@ -226,6 +427,12 @@ export namespace domain {
 	        this.creditsLocation = source["creditsLocation"];
 	        this.dynamicWrapWidth = source["dynamicWrapWidth"];
 	        this.locale = source["locale"];
+	        this.commandsPatchScript = source["commandsPatchScript"];
+	        this.commandCodesToPatch = source["commandCodesToPatch"];
+	        this.engine = source["engine"];
+	        this.fonts = this.convertValues(source["fonts"], FontSettings);
+	        this.dataFilesToPatch = source["dataFilesToPatch"];
+	        this.dataFieldsToPatch = this.convertValues(source["dataFieldsToPatch"], DataFileToPatch);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -246,6 +453,27 @@ export namespace domain {
 		    return a;
 		}
 	}
+	
+	
+	export class DictionaryIssue {
+	    key: string;
+	    translation: string;
+	    rule: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DictionaryIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.translation = source["translation"];
+	        this.rule = source["rule"];
+	        this.message = source["message"];
+	    }
+	}
+	
 	export class GameInfo {
 	    gameDir: string;
 	    exePath: string;
@@ -253,6 +481,7 @@ export namespace domain {
 	    jsPath: string;
 	    imgPath: string;
 	    gameTitle: string;
+	    engine: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new GameInfo(source);
@@ -266,6 +495,7 @@ export namespace domain {
 	        this.jsPath = source["jsPath"];
 	        this.imgPath = source["imgPath"];
 	        this.gameTitle = source["gameTitle"];
+	        this.engine = source["engine"];
 	    }
 	}
 	export class LocatedGame {
@@ -296,6 +526,56 @@ export namespace domain {
 	        this.playStatus = source["playStatus"];
 	    }
 	}
+	export class OverrideNode {
+	    name: string;
+	    path: string;
+	    size: number;
+	    isDir: boolean;
+	    children: OverrideNode[];
+	
+	    static createFrom(source: any = {}) {
+	        return new OverrideNode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.path = source["path"];
+	        this.size = source["size"];
+	        this.isDir = source["isDir"];
+	        this.children = this.convertValues(source["children"], OverrideNode);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class OverrideSettings {
+	    allowedPaths: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new OverrideSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.allowedPaths = source["allowedPaths"];
+	    }
+	}
 	
 	
 	export class PatchEntry {
@@ -320,11 +600,32 @@ export namespace domain {
 	        this.patchDownloadId = source["patchDownloadId"];
 	    }
 	}
+	export class PatchSignature {
+	    status: string;
+	    publisher: string;
+	    fingerprint: string;
+	    error: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new PatchSignature(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.status = source["status"];
+	        this.publisher = source["publisher"];
+	        this.fingerprint = source["fingerprint"];
+	        this.error = source["error"];
+	    }
+	}
 	export class PatchInfo {
 	    patchPath: string;
 	    dictionary: Record<string, string>;
+	    glossary: Record<string, string>;
 	    overrides: string[];
 	    config?: Config;
+	    issues: DictionaryIssue[];
+	    signature?: PatchSignature;
 	
 	    static createFrom(source: any = {}) {
 	        return new PatchInfo(source);
@@ -334,8 +635,81 @@ export namespace domain {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.patchPath = source["patchPath"];
 	        this.dictionary = source["dictionary"];
+	        this.glossary = source["glossary"];
 	        this.overrides = source["overrides"];
 	        this.config = this.convertValues(source["config"], Config);
+	        this.issues = this.convertValues(source["issues"], DictionaryIssue);
+	        this.signature = this.convertValues(source["signature"], PatchSignature);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PluginInspection {
+	    plugin: string;
+	    hasScript: boolean;
+	    scriptError: string;
+	    replaceRuleCount: number;
+	    parameterPathCount: number;
+	    configErrors: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new PluginInspection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.plugin = source["plugin"];
+	        this.hasScript = source["hasScript"];
+	        this.scriptError = source["scriptError"];
+	        this.replaceRuleCount = source["replaceRuleCount"];
+	        this.parameterPathCount = source["parameterPathCount"];
+	        this.configErrors = source["configErrors"];
+	    }
+	}
+	export class PatchInspection {
+	    patchPath: string;
+	    config?: Config;
+	    supportedVersion: boolean;
+	    dictionarySize: number;
+	    glossarySize: number;
+	    overrides: OverrideNode;
+	    unsafeOverrides: string[];
+	    plugins: PluginInspection[];
+	    issues: DictionaryIssue[];
+	    signature?: PatchSignature;
+	
+	    static createFrom(source: any = {}) {
+	        return new PatchInspection(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.patchPath = source["patchPath"];
+	        this.config = this.convertValues(source["config"], Config);
+	        this.supportedVersion = source["supportedVersion"];
+	        this.dictionarySize = source["dictionarySize"];
+	        this.glossarySize = source["glossarySize"];
+	        this.overrides = this.convertValues(source["overrides"], OverrideNode);
+	        this.unsafeOverrides = source["unsafeOverrides"];
+	        this.plugins = this.convertValues(source["plugins"], PluginInspection);
+	        this.issues = this.convertValues(source["issues"], DictionaryIssue);
+	        this.signature = this.convertValues(source["signature"], PatchSignature);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -358,6 +732,8 @@ export namespace domain {
 	}
 	
 	
+	
+	
 	export class ReleaseInfo {
 	    url: string;
 	    assets_url: string;
@@ -426,6 +802,73 @@ export namespace domain {
 		    return a;
 		}
 	}
+	export class TrustedKey {
+	    name: string;
+	    publicKey: string;
+	    embedded: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TrustedKey(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.publicKey = source["publicKey"];
+	        this.embedded = source["embedded"];
+	    }
+	}
+	export class SignatureSettings {
+	    policy: string;
+	    trustedKeys: TrustedKey[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SignatureSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.policy = source["policy"];
+	        this.trustedKeys = this.convertValues(source["trustedKeys"], TrustedKey);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TranslatorSettings {
+	    endpoint: string;
+	    apiKey: string;
+	    sourceLanguage: string;
+	    batchSize: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TranslatorSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.endpoint = source["endpoint"];
+	        this.apiKey = source["apiKey"];
+	        this.sourceLanguage = source["sourceLanguage"];
+	        this.batchSize = source["batchSize"];
+	    }
+	}
+	
 
 }
 
@@ -4,6 +4,7 @@ go 1.24.0
 
 require (
 	github.com/google/uuid v1.6.0
+	github.com/klauspost/compress v1.17.11
 	github.com/wailsapp/wails/v2 v2.11.0
 	github.com/yuin/gopher-lua v1.1.1
 )
@@ -0,0 +1,55 @@
+package domain
+
+// Backup storage modes
+const (
+	BackupModeFolder  = "folder"  // Raw copies in the .backup folder of the game
+	BackupModeArchive = "archive" // Compressed archives in the backup root, keyed by game id
+)
+
+// Backup archive formats
+const (
+	BackupFormatZip    = "zip"
+	BackupFormatTarZst = "tar.zst"
+)
+
+// BackupSettings defines where and how game backups are stored
+type BackupSettings struct {
+	Mode   string `json:"mode"`   // "folder" or "archive", defaults to "folder"
+	Format string `json:"format"` // "zip" or "tar.zst", used in archive mode
+	Root   string `json:"root"`   // Folder holding the archives, defaults to the app config folder
+}
+
+// BackupManifest describes a single backup generation
+type BackupManifest struct {
+	Generation   int          `json:"generation"`
+	CreatedAt    string       `json:"createdAt"` // ISO timestamp
+	PatchedAt    string       `json:"patchedAt"` // ISO timestamp, empty until the patch has been applied
+	Patch        BackupPatch  `json:"patch"`
+	Files        []BackupFile `json:"files"`        // Original files saved in this generation
+	CreatedFiles []string     `json:"createdFiles"` // Files the patch creates, removed on restore
+	CreatedDirs  []string     `json:"createdDirs"`  // Directories the patch creates, removed on restore when empty
+	PatchedFiles []BackupFile `json:"patchedFiles"` // Files as written by the patch
+}
+
+// BackupPatch records which patch a backup generation was taken for
+type BackupPatch struct {
+	FileName string `json:"fileName"`
+	SHA256   string `json:"sha256"`
+	Version  int    `json:"version"`
+	Locale   string `json:"locale"`
+}
+
+// BackupFile records a file and its hash
+type BackupFile struct {
+	Path   string `json:"path"` // Relative path from game directory
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// BackupVerification reports how a set of files compares to their recorded hashes
+type BackupVerification struct {
+	Generation int      `json:"generation"`
+	Valid      bool     `json:"valid"`
+	Modified   []string `json:"modified"`
+	Missing    []string `json:"missing"`
+}
@@ -0,0 +1,23 @@
+package domain
+
+// Delta patch file actions
+const (
+	DeltaActionPatch = "patch" // Binary delta against the original file
+	DeltaActionAdd   = "add"   // Full file that does not exist in the original game
+)
+
+// DeltaManifest describes the content of an exported delta patch
+type DeltaManifest struct {
+	Version   int         `json:"version"`
+	CreatedAt string      `json:"createdAt"` // ISO timestamp
+	Files     []DeltaFile `json:"files"`
+}
+
+// DeltaFile describes a single file of a delta patch
+type DeltaFile struct {
+	Path         string `json:"path"` // Relative path from game directory
+	Action       string `json:"action"`
+	SourceSHA256 string `json:"sourceSha256"` // Hash of the original file, empty for added files
+	TargetSHA256 string `json:"targetSha256"`
+	TargetSize   int64  `json:"targetSize"`
+}
@@ -1,5 +1,11 @@
 package domain
 
+// RPG Maker engines
+const (
+	EngineMV = "mv"
+	EngineMZ = "mz"
+)
+
 // GameInfo represents information about an RPG Maker game
 type GameInfo struct {
 	GameDir   string `json:"gameDir"`
@@ -8,6 +14,7 @@ type GameInfo struct {
 	JsPath    string `json:"jsPath"`
 	ImgPath   string `json:"imgPath"`
 	GameTitle string `json:"gameTitle"`
+	Engine    string `json:"engine"` // EngineMV or EngineMZ
 }
 
 // LocatedGame represents a game stored in the user's collection
@@ -25,8 +32,12 @@ type LocatedGame struct {
 
 // PersistentData holds user's persistent application data
 type PersistentData struct {
-	LocatedGames []LocatedGame `json:"locatedGames"`
-	GamesPerRow  int           `json:"gamesPerRow"` // 3 or 4, defaults to 3
+	LocatedGames       []LocatedGame      `json:"locatedGames"`
+	GamesPerRow        int                `json:"gamesPerRow"` // 3 or 4, defaults to 3
+	BackupSettings     BackupSettings     `json:"backupSettings"`
+	TranslatorSettings TranslatorSettings `json:"translatorSettings"`
+	SignatureSettings  SignatureSettings  `json:"signatureSettings"`
+	OverrideSettings   OverrideSettings   `json:"overrideSettings"`
 }
 
 // PatchEntry represents a patch available for download
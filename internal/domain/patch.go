@@ -1,38 +1,112 @@
 package domain
 
-import "encoding/json"
-
 // PatchInfo contains all information about a patch file
 type PatchInfo struct {
 	PatchPath  string            `json:"patchPath"`
 	Dictionary map[string]string `json:"dictionary"`
+	Glossary   map[string]string `json:"glossary"` // Source term to required translation
 	Overrides  []string          `json:"overrides"`
 	Config     *Config           `json:"config"`
+	Issues     []DictionaryIssue `json:"issues"` // Problems found in the dictionary, not applied
+	Signature  *PatchSignature   `json:"signature"`
+}
+
+// PatchInspection describes what a patch will do to a game, without applying it
+type PatchInspection struct {
+	PatchPath        string             `json:"patchPath"`
+	Config           *Config            `json:"config"`
+	SupportedVersion bool               `json:"supportedVersion"` // False when the patcher must be updated to apply it
+	DictionarySize   int                `json:"dictionarySize"`
+	GlossarySize     int                `json:"glossarySize"`
+	Overrides        OverrideNode       `json:"overrides"`       // Root of the override file tree
+	UnsafeOverrides  []string           `json:"unsafeOverrides"` // Overrides refused when applying, with the reason
+	Plugins          []PluginInspection `json:"plugins"`
+	Issues           []DictionaryIssue  `json:"issues"`
+	Signature        *PatchSignature    `json:"signature"`
+}
+
+// OverrideNode is a file or folder of the override file tree of a patch
+type OverrideNode struct {
+	Name     string         `json:"name"`
+	Path     string         `json:"path"` // Relative to the game folder
+	Size     int64          `json:"size"` // Uncompressed size, the total of the children for folders
+	IsDir    bool           `json:"isDir"`
+	Children []OverrideNode `json:"children"`
+}
+
+// PluginInspection describes how a patch changes a plugin
+type PluginInspection struct {
+	Plugin             string   `json:"plugin"`
+	HasScript          bool     `json:"hasScript"`
+	ScriptError        string   `json:"scriptError"` // Syntax error of the parameters patch script, if any
+	ReplaceRuleCount   int      `json:"replaceRuleCount"`
+	ParameterPathCount int      `json:"parameterPathCount"`
+	ConfigErrors       []string `json:"configErrors"` // Invalid replace rules and parameter paths
+}
+
+// OverrideSettings restricts where in the game folder patches may write override files
+type OverrideSettings struct {
+	AllowedPaths []string `json:"allowedPaths"` // Subtrees of the game folder, e.g. "img/pictures". Empty allows the whole folder.
 }
 
 // Config defines patch configuration and rules
 type Config struct {
-	VariablesToPatch  []int              `json:"variablesToPatch"`
-	WrapWidth         int                `json:"wrapWidth"`
-	Version           int                `json:"version"`
-	ParametersToPatch []ParameterToPatch `json:"parametersToPatch"`
-	PluginsToPatch    []PluginToPatch    `json:"pluginsToPatch"`
-	CreditsLocation   string             `json:"creditsLocation"`
-	DynamicWrapWidth  bool               `json:"dynamicWrapWidth"`
-	Locale            string             `json:"locale"`
+	VariablesToPatch    []int              `json:"variablesToPatch"`
+	WrapWidth           int                `json:"wrapWidth"`
+	Version             int                `json:"version"`
+	ParametersToPatch   []ParameterToPatch `json:"parametersToPatch"`
+	PluginsToPatch      []PluginToPatch    `json:"pluginsToPatch"`
+	CreditsLocation     string             `json:"creditsLocation"`
+	DynamicWrapWidth    bool               `json:"dynamicWrapWidth"`
+	Locale              string             `json:"locale"`
+	CommandsPatchScript string             `json:"commandsPatchScript"` // Lua script defining patchCommand(command, context)
+	CommandCodesToPatch []int              `json:"commandCodesToPatch"` // Event command codes passed to patchCommand
+	Engine              string             `json:"engine"`              // Engine the patch was made for, "mv" or "mz". Checked against the game when set.
+	Fonts               *FontSettings      `json:"fonts"`               // MZ only, fonts of System.json
+	DataFilesToPatch    []string           `json:"dataFilesToPatch"`    // Optional data files to translate: "mapinfos", "animations" and "tilesets"
+	DataFieldsToPatch   []DataFileToPatch  `json:"dataFieldsToPatch"`   // Fields to translate besides the built-in ones, e.g. of plugin data files
+}
+
+// DataFileToPatch lists fields to translate in a data file
+type DataFileToPatch struct {
+	File   string             `json:"file"` // Name in the data folder, e.g. "Quests" for data/Quests.json
+	Fields []DataFieldToPatch `json:"fields"`
+}
+
+// DataFieldToPatch defines a field of a data file to translate
+type DataFieldToPatch struct {
+	Path string `json:"path"` // JSONPath-like, e.g. "$[*].objectives[*].text". Strings holding JSON are descended into.
+	Type   string `json:"type"`             // "text", "wrap" or "nonewline"
+	Window string `json:"window,omitempty"` // "message" or "help" to check the length of translations against that window
+}
+
+// FontSettings replaces the fonts of an MZ game, e.g. with ones covering the target language.
+// Empty fields keep the fonts of the game.
+type FontSettings struct {
+	MainFontFilename   string `json:"mainFontFilename"`   // File in the fonts folder
+	NumberFontFilename string `json:"numberFontFilename"` // File in the fonts folder
+	FallbackFonts      string `json:"fallbackFonts"`      // CSS font family list
+	FontSize           int    `json:"fontSize"`
 }
 
 // PluginToPatch defines how to patch a specific plugin
 type PluginToPatch struct {
-	Plugin                string              `json:"plugin"`
-	ParametersPatchScript string              `json:"parametersPatchScript"` // Lua script
-	ReplaceRules          []PluginReplaceRule `json:"replaceRules"`
+	Plugin                string                 `json:"plugin"`
+	ParametersPatchScript string                 `json:"parametersPatchScript"` // Lua script
+	ReplaceRules          []PluginReplaceRule    `json:"replaceRules"`
+	TranslateStrings      bool                   `json:"translateStrings"` // Translate the string literals of the plugin source found in the dictionary
+	ParameterPaths        []ParameterPathToPatch `json:"parameterPaths"`   // Parameters to translate without a script, e.g. "$.messages[*].text"
 }
 
 // PluginReplaceRule defines a text replacement rule for plugin files
 type PluginReplaceRule struct {
-	Match   string `json:"match"`
-	Replace string `json:"replace"`
+	Match           string `json:"match"`
+	Replace         string `json:"replace"`
+	Regex           bool   `json:"regex"`           // Match is a regular expression, Replace may use capture groups like ${1}
+	ExpectedMatches int    `json:"expectedMatches"` // Number of matches expected, 0 for at least one
+	Strict          bool   `json:"strict"`          // Fail instead of warning when the number of matches differs
+	Function        string `json:"function"`        // Only replace inside the body of this function, e.g. "Window_Base.prototype.drawText"
+	File            string `json:"file"`            // Target relative to the js folder, e.g. "rmmz_windows.js". Defaults to the plugin file.
 }
 
 // ParameterToPatch defines how to patch plugin command parameters
@@ -40,19 +114,12 @@ type ParameterToPatch struct {
 	Plugin                string                 `json:"plugin"`
 	Function              string                 `json:"function"`
 	RootType              string                 `json:"rootType"`
-	ParameterPathsToPatch []ParameterPathToPatch `json:"parameterPathsToPatch"`
+	ParameterPathsToPatch []ParameterPathToPatch `json:"parameterPathsToPatch"` // Texts to translate, the other strings are left as is
+	Recursive             bool                   `json:"recursive"`             // Without paths, translate every string of an array or object root
 }
 
 // ParameterPathToPatch defines a specific parameter path to translate
 type ParameterPathToPatch struct {
-	Path string `json:"path"`
-	Type string `json:"type"`
-}
-
-// PluginData represents a plugin entry in plugins.js
-type PluginData struct {
-	Name        string          `json:"name"`
-	Description string          `json:"description"`
-	Status      bool            `json:"status"`
-	Parameters  json.RawMessage `json:"parameters"`
+	Path string `json:"path"` // JSONPath-like, e.g. "$.list[*].name". Strings holding JSON are descended into.
+	Type string `json:"type"` // "text" or "wrap"
 }
@@ -0,0 +1,31 @@
+package rpgmaker
+
+import (
+	"encoding/json"
+	"htpatcher/internal/util"
+)
+
+// Animation represents a battle animation. Its frames and timings differ between MV and MZ and are kept as extras.
+type Animation struct {
+	ID     int                        `json:"id"`
+	Name   string                     `json:"name"`
+	Extras map[string]json.RawMessage `json:"-"`
+}
+
+func (a *Animation) UnmarshalJSON(data []byte) error {
+	type Alias Animation
+	aux := (*Alias)(a)
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	a.Extras, _ = util.UnmarshalExtras(data, util.GetJSONFieldNames(a))
+	return nil
+}
+
+func (a Animation) MarshalJSON() ([]byte, error) {
+	type Alias Animation
+	return util.MarshalWithExtras((*Alias)(&a), a.Extras)
+}
+
+// AnimationsData is an array of animations
+type AnimationsData []*Animation
@@ -31,9 +31,10 @@ func (t Trait) MarshalJSON() ([]byte, error) {
 
 // EventCommand represents a command in an event or troop page
 type EventCommand struct {
-	Code       int   `json:"code"`
-	Indent     int   `json:"indent"`
-	Parameters []any `json:"parameters"`
+	Code       int                        `json:"code"`
+	Indent     int                        `json:"indent"`
+	Parameters []any                      `json:"parameters"`
+	Extras     map[string]json.RawMessage `json:"-"`
 }
 
 // UnmarshalJSON custom unmarshals EventCommand to preserve object key order in Parameters.
@@ -76,11 +77,15 @@ func (e *EventCommand) UnmarshalJSON(data []byte) error {
 			e.Parameters = params
 
 		default:
-			// Skip unknown fields
-			var skip any
-			if err := dec.Decode(&skip); err != nil {
+			// Keep unknown fields, e.g. added by plugins or editors
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
 				return err
 			}
+			if e.Extras == nil {
+				e.Extras = make(map[string]json.RawMessage)
+			}
+			e.Extras[key] = raw
 		}
 	}
 
@@ -92,6 +97,11 @@ func (e *EventCommand) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+func (e EventCommand) MarshalJSON() ([]byte, error) {
+	type Alias EventCommand
+	return util.MarshalWithExtras((*Alias)(&e), e.Extras)
+}
+
 // parseParametersArray parses the parameters array, converting objects to *util.OrderedMap.
 func parseParametersArray(dec *json.Decoder) ([]any, error) {
 	// Read opening bracket
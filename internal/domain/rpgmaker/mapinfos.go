@@ -0,0 +1,36 @@
+package rpgmaker
+
+import (
+	"encoding/json"
+	"htpatcher/internal/util"
+)
+
+// MapInfo represents an entry of the map tree (MapInfos.json)
+type MapInfo struct {
+	ID       int                        `json:"id"`
+	Expanded bool                       `json:"expanded"`
+	Name     string                     `json:"name"`
+	Order    int                        `json:"order"`
+	ParentId int                        `json:"parentId"`
+	ScrollX  float64                    `json:"scrollX"`
+	ScrollY  float64                    `json:"scrollY"`
+	Extras   map[string]json.RawMessage `json:"-"`
+}
+
+func (m *MapInfo) UnmarshalJSON(data []byte) error {
+	type Alias MapInfo
+	aux := (*Alias)(m)
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	m.Extras, _ = util.UnmarshalExtras(data, util.GetJSONFieldNames(m))
+	return nil
+}
+
+func (m MapInfo) MarshalJSON() ([]byte, error) {
+	type Alias MapInfo
+	return util.MarshalWithExtras((*Alias)(&m), m.Extras)
+}
+
+// MapInfosData is an array of map infos
+type MapInfosData []*MapInfo
@@ -0,0 +1,108 @@
+package rpgmaker
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// goldenTypes returns a new value of the type each golden data file is decoded into
+var goldenTypes = map[string]func() any{
+	"Actors.json":       func() any { return &ActorsData{} },
+	"Animations.json":   func() any { return &AnimationsData{} },
+	"Armors.json":       func() any { return &ArmorsData{} },
+	"Classes.json":      func() any { return &ClassesData{} },
+	"CommonEvents.json": func() any { return &CommonEventsData{} },
+	"Enemies.json":      func() any { return &EnemiesData{} },
+	"Items.json":        func() any { return &ItemsData{} },
+	"Map001.json":       func() any { return &MapData{} },
+	"MapInfos.json":     func() any { return &MapInfosData{} },
+	"Skills.json":       func() any { return &SkillsData{} },
+	"States.json":       func() any { return &StatesData{} },
+	"System.json":       func() any { return &System{} },
+	"Tilesets.json":     func() any { return &TilesetsData{} },
+	"Troops.json":       func() any { return &TroopsData{} },
+	"Weapons.json":      func() any { return &WeaponsData{} },
+}
+
+// TestRoundTrip checks that decoding and encoding the golden MV and MZ data files loses nothing
+func TestRoundTrip(t *testing.T) {
+	for _, engine := range []string{"mv", "mz"} {
+		for name, newValue := range goldenTypes {
+			t.Run(engine+"/"+name, func(t *testing.T) {
+				original, err := os.ReadFile(filepath.Join("testdata", engine, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				value := newValue()
+				if err := json.Unmarshal(original, value); err != nil {
+					t.Fatalf("unmarshal: %v", err)
+				}
+				encoded, err := json.Marshal(value)
+				if err != nil {
+					t.Fatalf("marshal: %v", err)
+				}
+				assertSameJSON(t, original, encoded)
+			})
+		}
+	}
+}
+
+// TestRoundTripUnknownFiles checks that no golden data file lacks a type
+func TestRoundTripUnknownFiles(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		if _, ok := goldenTypes[filepath.Base(file)]; !ok {
+			t.Errorf("%s has no type to decode it", file)
+		}
+	}
+}
+
+// FuzzParseParameterValue checks that event command parameters decode to the values encoding/json finds
+func FuzzParseParameterValue(f *testing.F) {
+	for _, seed := range []string{
+		`"text"`, `1.5`, `-0`, `true`, `null`, `[]`, `{}`,
+		`["Actor1",0,0,2,"ハロルド"]`,
+		`{"b":1,"a":{"d":[1,{"e":null}],"c":"x"}}`,
+		`{"a":1,"a":2}`,
+		`"薬\n\\C[2]"`,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var expected any
+		if json.Unmarshal(data, &expected) != nil {
+			return
+		}
+		value, err := parseParameterValue(json.NewDecoder(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatalf("parse %q: %v", data, err)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("marshal %q: %v", data, err)
+		}
+		assertSameJSON(t, data, encoded)
+	})
+}
+
+// assertSameJSON fails when two documents do not decode to the same values
+func assertSameJSON(t *testing.T, expected []byte, actual []byte) {
+	t.Helper()
+	var want, got any
+	if err := json.Unmarshal(expected, &want); err != nil {
+		t.Fatalf("expected: %v", err)
+	}
+	if err := json.Unmarshal(actual, &got); err != nil {
+		t.Fatalf("actual: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("round trip changed the data\nexpected: %s\nactual:   %s", expected, actual)
+	}
+}
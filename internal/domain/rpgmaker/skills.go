@@ -78,7 +78,7 @@ type Skill struct {
 	SuccessRate      int                        `json:"successRate"`
 	TpCost           int                        `json:"tpCost"`
 	TpGain           int                        `json:"tpGain"`
-	MessageType      int                        `json:"messageType"`
+	MessageType      *int                       `json:"messageType,omitempty"` // MZ only
 	Extras           map[string]json.RawMessage `json:"-"`
 }
 
@@ -30,7 +30,7 @@ type State struct {
 	Restriction         int                        `json:"restriction"`
 	StepsToRemove       int                        `json:"stepsToRemove"`
 	Traits              []Trait                    `json:"traits"`
-	MessageType         int                        `json:"messageType"`
+	MessageType         *int                       `json:"messageType,omitempty"` // MZ only
 	Extras              map[string]json.RawMessage `json:"-"`
 }
 
@@ -123,7 +123,7 @@ func (t TitleCommandWindow) MarshalJSON() ([]byte, error) {
 	return util.MarshalWithExtras((*Alias)(&t), t.Extras)
 }
 
-// Advanced contains advanced game settings
+// Advanced contains advanced game settings, MZ only
 type Advanced struct {
 	GameId             int                        `json:"gameId"`
 	ScreenWidth        int                        `json:"screenWidth"`
@@ -158,7 +158,7 @@ func (a Advanced) MarshalJSON() ([]byte, error) {
 type TermsMessages struct {
 	AlwaysDash      string                     `json:"alwaysDash"`
 	CommandRemember string                     `json:"commandRemember"`
-	TouchUI         string                     `json:"touchUI"`
+	TouchUI         *string                    `json:"touchUI,omitempty"` // MZ only
 	BgmVolume       string                     `json:"bgmVolume"`
 	BgsVolume       string                     `json:"bgsVolume"`
 	MeVolume        string                     `json:"meVolume"`
@@ -169,7 +169,7 @@ type TermsMessages struct {
 	SaveMessage     string                     `json:"saveMessage"`
 	LoadMessage     string                     `json:"loadMessage"`
 	File            string                     `json:"file"`
-	Autosave        string                     `json:"autosave"`
+	Autosave        *string                    `json:"autosave,omitempty"` // MZ only
 	PartyName       string                     `json:"partyName"`
 	Emerge          string                     `json:"emerge"`
 	Preemptive      string                     `json:"preemptive"`
@@ -253,7 +253,7 @@ func (t Terms) MarshalJSON() ([]byte, error) {
 
 // System represents the system data (System.json)
 type System struct {
-	Advanced           Advanced                   `json:"advanced"`
+	Advanced           *Advanced                  `json:"advanced,omitempty"` // MZ only
 	Airship            Vehicle                    `json:"airship"`
 	ArmorTypes         []string                   `json:"armorTypes"`
 	AttackMotions      []AttackMotion             `json:"attackMotions"`
@@ -262,7 +262,7 @@ type System struct {
 	Battleback2Name    string                     `json:"battleback2Name"`
 	BattlerHue         int                        `json:"battlerHue"`
 	BattlerName        string                     `json:"battlerName"`
-	BattleSystem       int                        `json:"battleSystem"`
+	BattleSystem       *int                       `json:"battleSystem,omitempty"` // MZ only
 	Boat               Vehicle                    `json:"boat"`
 	CurrencyUnit       string                     `json:"currencyUnit"`
 	DefeatMe           Bgm                        `json:"defeatMe"`
@@ -271,17 +271,17 @@ type System struct {
 	EquipTypes         []string                   `json:"equipTypes"`
 	GameTitle          string                     `json:"gameTitle"`
 	GameoverMe         Bgm                        `json:"gameoverMe"`
-	ItemCategories     []bool                     `json:"itemCategories"`
+	ItemCategories     []bool                     `json:"itemCategories,omitempty"` // MZ only
 	Locale             string                     `json:"locale"`
 	MagicSkills        []int                      `json:"magicSkills"`
-	MenuCommands       []bool                     `json:"menuCommands"`
-	OptAutosave        bool                       `json:"optAutosave"`
+	MenuCommands       []bool                     `json:"menuCommands,omitempty"` // MZ only
+	OptAutosave        *bool                      `json:"optAutosave,omitempty"`  // MZ only
 	OptDisplayTp       bool                       `json:"optDisplayTp"`
 	OptDrawTitle       bool                       `json:"optDrawTitle"`
 	OptExtraExp        bool                       `json:"optExtraExp"`
 	OptFloorDeath      bool                       `json:"optFloorDeath"`
 	OptFollowers       bool                       `json:"optFollowers"`
-	OptKeyItemsNumber  bool                       `json:"optKeyItemsNumber"`
+	OptKeyItemsNumber  *bool                      `json:"optKeyItemsNumber,omitempty"` // MZ only
 	OptSideView        bool                       `json:"optSideView"`
 	OptSlipDeath       bool                       `json:"optSlipDeath"`
 	OptTransparent     bool                       `json:"optTransparent"`
@@ -299,13 +299,13 @@ type System struct {
 	Title1Name         string                     `json:"title1Name"`
 	Title2Name         string                     `json:"title2Name"`
 	TitleBgm           Bgm                        `json:"titleBgm"`
-	TitleCommandWindow TitleCommandWindow         `json:"titleCommandWindow"`
+	TitleCommandWindow *TitleCommandWindow        `json:"titleCommandWindow,omitempty"` // MZ only
 	Variables          []string                   `json:"variables"`
 	VersionId          int                        `json:"versionId"`
 	VictoryMe          Bgm                        `json:"victoryMe"`
 	WeaponTypes        []string                   `json:"weaponTypes"`
 	WindowTone         []int                      `json:"windowTone"`
-	TileSize           int                        `json:"tileSize"`
+	TileSize           *int                       `json:"tileSize,omitempty"` // MZ only
 	HasEncryptedImages bool                       `json:"hasEncryptedImages"`
 	HasEncryptedAudio  bool                       `json:"hasEncryptedAudio"`
 	EncryptionKey      string                     `json:"encryptionKey"`
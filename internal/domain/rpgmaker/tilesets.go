@@ -0,0 +1,35 @@
+package rpgmaker
+
+import (
+	"encoding/json"
+	"htpatcher/internal/util"
+)
+
+// Tileset represents a tileset
+type Tileset struct {
+	ID           int                        `json:"id"`
+	Flags        []int                      `json:"flags"`
+	Mode         int                        `json:"mode"`
+	Name         string                     `json:"name"`
+	Note         string                     `json:"note"`
+	TilesetNames []string                   `json:"tilesetNames"`
+	Extras       map[string]json.RawMessage `json:"-"`
+}
+
+func (t *Tileset) UnmarshalJSON(data []byte) error {
+	type Alias Tileset
+	aux := (*Alias)(t)
+	if err := json.Unmarshal(data, aux); err != nil {
+		return err
+	}
+	t.Extras, _ = util.UnmarshalExtras(data, util.GetJSONFieldNames(t))
+	return nil
+}
+
+func (t Tileset) MarshalJSON() ([]byte, error) {
+	type Alias Tileset
+	return util.MarshalWithExtras((*Alias)(&t), t.Extras)
+}
+
+// TilesetsData is an array of tilesets
+type TilesetsData []*Tileset
@@ -0,0 +1,52 @@
+package domain
+
+// Signature policies
+const (
+	SignaturePolicyWarn    = "warn"    // Unsigned and untrusted patches load with a warning
+	SignaturePolicyRequire = "require" // Only patches signed by a trusted key load
+)
+
+// Signature statuses of a patch
+const (
+	SignatureStatusUnsigned  = "unsigned"
+	SignatureStatusTrusted   = "trusted"   // Signed by a trusted key
+	SignatureStatusUntrusted = "untrusted" // Validly signed by a key that is not trusted
+	SignatureStatusInvalid   = "invalid"   // Tampered with since it was signed
+)
+
+// SignatureSettings defines which patches are trusted
+type SignatureSettings struct {
+	Policy      string       `json:"policy"`      // "warn" or "require", defaults to "warn"
+	TrustedKeys []TrustedKey `json:"trustedKeys"` // Keys added by the user
+}
+
+// TrustedKey is the public key of a trusted patch publisher
+type TrustedKey struct {
+	Name      string `json:"name"`
+	PublicKey string `json:"publicKey"` // Base64 encoded ed25519 public key
+	Embedded  bool   `json:"embedded"`  // Shipped with the app, cannot be removed
+}
+
+// PatchManifest lists the hashes of every file of a signed patch
+type PatchManifest struct {
+	Version   int               `json:"version"`
+	Publisher string            `json:"publisher"`
+	PublicKey string            `json:"publicKey"` // Base64 encoded ed25519 public key
+	CreatedAt string            `json:"createdAt"` // ISO timestamp
+	Files     map[string]string `json:"files"`     // Archive entry to SHA-256 hex
+}
+
+// PatchSignature is the result of verifying the signature of a patch
+type PatchSignature struct {
+	Status      string `json:"status"`
+	Publisher   string `json:"publisher"`   // Name of the trusted key, or the publisher claimed by the manifest
+	Fingerprint string `json:"fingerprint"` // Short fingerprint of the signing key
+	Error       string `json:"error"`       // Why the signature is invalid
+}
+
+// SigningKey is an ed25519 key pair used to sign patches
+type SigningKey struct {
+	Publisher  string `json:"publisher"`
+	PublicKey  string `json:"publicKey"`  // Base64 encoded
+	PrivateKey string `json:"privateKey"` // Base64 encoded, never leaves the key file
+}
@@ -0,0 +1,59 @@
+package domain
+
+// Translation interchange formats
+const (
+	InterchangeFormatPO      = "po"
+	InterchangeFormatXLIFF12 = "xliff12"
+	InterchangeFormatXLIFF20 = "xliff20"
+	InterchangeFormatCSV     = "csv"
+	InterchangeFormatTSV     = "tsv"
+)
+
+// Windows showing source texts, which limit how long their translations can be
+const (
+	SourceWindowMessage     = "message"     // Message window of Show Text
+	SourceWindowMessageFace = "messageFace" // Message window of Show Text next to a face
+	SourceWindowHelp        = "help"        // Help window of the menus, showing descriptions and profiles
+)
+
+// SourceText is a translatable text found in the game data
+type SourceText struct {
+	Text     string `json:"text"`
+	File     string `json:"file"`             // Data file name, e.g. "Map001.json"
+	Location string `json:"location"`         // Where in the file, e.g. "EV003 Door, page 1"
+	Speaker  string `json:"speaker"`          // Speaker of a dialogue line, if any
+	Window   string `json:"window,omitempty"` // Window showing the text when its size is known
+}
+
+// TranslationUnit is a dictionary entry together with the source text it translates
+type TranslationUnit struct {
+	Key               string   `json:"key"`    // Dictionary key
+	Source            string   `json:"source"` // Original text, the key itself when not found in the game data
+	Target            string   `json:"target"` // Translation, empty when not translated yet
+	References        []string `json:"references"`
+	Speakers          []string `json:"speakers"`
+	MachineTranslated bool     `json:"machineTranslated"` // Draft translation that still needs review
+}
+
+// TranslatorSettings configures the machine translation backend used to pre-fill dictionaries
+type TranslatorSettings struct {
+	Endpoint       string `json:"endpoint"`       // URL of a backend speaking the generic JSON protocol
+	APIKey         string `json:"apiKey"`         // Sent as a bearer token when set
+	SourceLanguage string `json:"sourceLanguage"` // Defaults to "ja"
+	BatchSize      int    `json:"batchSize"`      // Texts per request, defaults to 50
+}
+
+// Dictionary issue rules
+const (
+	IssueRuleGlossary    = "glossary"
+	IssueRuleEscapeCodes = "escapeCodes"
+	IssueRuleLength      = "length"
+)
+
+// DictionaryIssue is a problem found in a dictionary entry
+type DictionaryIssue struct {
+	Key         string `json:"key"`
+	Translation string `json:"translation"`
+	Rule        string `json:"rule"`
+	Message     string `json:"message"`
+}
@@ -0,0 +1,157 @@
+package interchange
+
+import (
+	"encoding/csv"
+	"fmt"
+	"htpatcher/internal/domain"
+	"io"
+	"slices"
+	"strings"
+)
+
+// CSV and TSV columns. Speakers and locations are not read back.
+var csvHeader = []string{"key", "source", "target", "machineTranslated", "speakers", "locations"}
+
+// writeCSV writes units as a CSV or TSV file with a header row
+func writeCSV(w io.Writer, units []domain.TranslationUnit, comma rune) error {
+	// A BOM lets spreadsheet applications detect UTF-8
+	if comma == ',' {
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return err
+		}
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, unit := range units {
+		record := []string{
+			unit.Key,
+			unit.Source,
+			unit.Target,
+			csvBool(unit.MachineTranslated),
+			strings.Join(unit.Speakers, "\n"),
+			strings.Join(unit.References, "\n"),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// readCSV reads units from a CSV or TSV file, locating the columns by their header
+func readCSV(r io.Reader, comma rune) ([]domain.TranslationUnit, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	records, err := parseCSV(strings.TrimPrefix(string(data), "\ufeff"), comma)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header row")
+	}
+
+	header := records[0]
+	keyColumn := slices.Index(header, "key")
+	sourceColumn := slices.Index(header, "source")
+	targetColumn := slices.Index(header, "target")
+	machineColumn := slices.Index(header, "machineTranslated")
+	if targetColumn < 0 || (keyColumn < 0 && sourceColumn < 0) {
+		return nil, fmt.Errorf("missing key, source or target column")
+	}
+
+	column := func(record []string, index int) string {
+		if index < 0 || index >= len(record) {
+			return ""
+		}
+		return record[index]
+	}
+
+	units := []domain.TranslationUnit{}
+	for _, record := range records[1:] {
+		units = append(units, domain.TranslationUnit{
+			Key:               column(record, keyColumn),
+			Source:            column(record, sourceColumn),
+			Target:            column(record, targetColumn),
+			MachineTranslated: column(record, machineColumn) == csvBool(true),
+		})
+	}
+	return units, nil
+}
+
+// csvBool formats a flag column
+func csvBool(value bool) string {
+	if value {
+		return "yes"
+	}
+	return ""
+}
+
+// parseCSV splits CSV data into records.
+// Unlike encoding/csv it keeps carriage returns inside quoted fields, which translations may contain.
+func parseCSV(data string, comma rune) ([][]string, error) {
+	records := [][]string{}
+	record := []string{}
+	var field strings.Builder
+	inQuotes := false
+	fieldStarted := false
+
+	endRecord := func() {
+		record = append(record, field.String())
+		field.Reset()
+		fieldStarted = false
+		// Skip blank lines
+		if len(record) > 1 || record[0] != "" {
+			records = append(records, record)
+		}
+		record = []string{}
+	}
+
+	runes := []rune(data)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if inQuotes {
+			if c == '"' {
+				if i+1 < len(runes) && runes[i+1] == '"' {
+					field.WriteRune('"')
+					i++
+				} else {
+					inQuotes = false
+				}
+			} else {
+				field.WriteRune(c)
+			}
+			continue
+		}
+
+		switch {
+		case c == '"' && !fieldStarted:
+			inQuotes = true
+			fieldStarted = true
+		case c == comma:
+			record = append(record, field.String())
+			field.Reset()
+			fieldStarted = false
+		case c == '\r' && i+1 < len(runes) && runes[i+1] == '\n':
+			// Record separator, handled with the newline
+		case c == '\n':
+			endRecord()
+		default:
+			field.WriteRune(c)
+			fieldStarted = true
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("unterminated quoted field")
+	}
+	if fieldStarted || len(record) > 0 {
+		endRecord()
+	}
+	return records, nil
+}
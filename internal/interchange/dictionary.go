@@ -0,0 +1,178 @@
+// Package interchange converts patch dictionaries to and from the formats used by CAT tools
+package interchange
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"htpatcher/internal/domain"
+	"htpatcher/internal/util"
+	"slices"
+)
+
+// ReadDictionary parses dictionary.json keeping the order of its entries
+func ReadDictionary(data []byte) (*util.OrderedMap, error) {
+	dictionary := util.NewOrderedMap()
+	if err := json.Unmarshal(data, dictionary); err != nil {
+		return nil, err
+	}
+	for _, key := range dictionary.Keys {
+		if _, ok := dictionary.Values[key].(string); !ok {
+			return nil, fmt.Errorf("dictionary entry %q is not a string", key)
+		}
+	}
+	return dictionary, nil
+}
+
+// BuildUnits pairs the dictionary entries with the source texts found in the game.
+// Dictionary entries come first in dictionary order, followed by untranslated texts in game order.
+func BuildUnits(dictionary *util.OrderedMap, sources []domain.SourceText) []domain.TranslationUnit {
+	units := []domain.TranslationUnit{}
+	indexes := make(map[string]int)
+	for _, key := range dictionary.Keys {
+		if _, exists := indexes[key]; exists {
+			continue
+		}
+		indexes[key] = len(units)
+		units = append(units, domain.TranslationUnit{
+			Key:        key,
+			Source:     key,
+			Target:     dictionary.Values[key].(string),
+			References: []string{},
+			Speakers:   []string{},
+		})
+	}
+
+	found := make(map[string]bool)
+	for _, source := range sources {
+		key := util.GetTranslationKey(source.Text)
+		index, exists := indexes[key]
+		if !exists {
+			index = len(units)
+			indexes[key] = index
+			units = append(units, domain.TranslationUnit{
+				Key:        key,
+				References: []string{},
+				Speakers:   []string{},
+			})
+		}
+
+		unit := &units[index]
+		if !found[key] {
+			found[key] = true
+			unit.Source = source.Text
+		}
+		reference := source.File + ": " + source.Location
+		if !slices.Contains(unit.References, reference) {
+			unit.References = append(unit.References, reference)
+		}
+		if source.Speaker != "" && !slices.Contains(unit.Speakers, source.Speaker) {
+			unit.Speakers = append(unit.Speakers, source.Speaker)
+		}
+	}
+	return units
+}
+
+// BuildDictionary turns translation units back into a dictionary.
+// Units without a translation are skipped unless their key is in the original dictionary,
+// so entries translated to an empty string survive an export and import. The first translation of a key wins.
+func BuildDictionary(units []domain.TranslationUnit, original *util.OrderedMap) *util.OrderedMap {
+	dictionary := util.NewOrderedMap()
+	for _, unit := range units {
+		key := unit.Key
+		if key == "" {
+			key = util.GetTranslationKey(unit.Source)
+		}
+		if unit.Target == "" {
+			if _, exists := original.Get(key); !exists {
+				continue
+			}
+		}
+		if _, exists := dictionary.Get(key); !exists {
+			dictionary.Set(key, unit.Target)
+		}
+	}
+	return dictionary
+}
+
+// EncodeDictionary serializes a dictionary in the style of the original dictionary.json.
+// The original bytes are returned unchanged when the dictionary has the same entries in the same order.
+func EncodeDictionary(dictionary *util.OrderedMap, original []byte) ([]byte, error) {
+	if existing, err := ReadDictionary(original); err == nil && sameDictionary(existing, dictionary) {
+		return original, nil
+	}
+
+	// Follow the indentation, HTML escaping and trailing newline of the original
+	indent := detectIndent(original)
+	escapeHTML := bytes.Contains(original, []byte(`\u003c`)) || bytes.Contains(original, []byte(`\u003e`)) || bytes.Contains(original, []byte(`\u0026`))
+	encodeString := func(s string) ([]byte, error) {
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(escapeHTML)
+		if err := encoder.Encode(s); err != nil {
+			return nil, err
+		}
+		return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+	}
+
+	var out bytes.Buffer
+	out.WriteString("{")
+	for i, key := range dictionary.Keys {
+		if i > 0 {
+			out.WriteString(",")
+		}
+		keyBytes, err := encodeString(key)
+		if err != nil {
+			return nil, err
+		}
+		valueBytes, err := encodeString(dictionary.Values[key].(string))
+		if err != nil {
+			return nil, err
+		}
+		if indent != "" {
+			out.WriteString("\n" + indent)
+			out.Write(keyBytes)
+			out.WriteString(": ")
+		} else {
+			out.Write(keyBytes)
+			out.WriteString(":")
+		}
+		out.Write(valueBytes)
+	}
+	if indent != "" && len(dictionary.Keys) > 0 {
+		out.WriteString("\n")
+	}
+	out.WriteString("}")
+	if bytes.HasSuffix(original, []byte("\n")) {
+		out.WriteString("\n")
+	}
+	return out.Bytes(), nil
+}
+
+// sameDictionary reports whether two dictionaries have the same entries in the same order
+func sameDictionary(a *util.OrderedMap, b *util.OrderedMap) bool {
+	if !slices.Equal(a.Keys, b.Keys) {
+		return false
+	}
+	for _, key := range a.Keys {
+		if a.Values[key] != b.Values[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// detectIndent returns the indentation of the first entry of a JSON object, or "" when it is compact
+func detectIndent(data []byte) string {
+	start := bytes.IndexByte(data, '{')
+	if start < 0 {
+		return ""
+	}
+	rest := data[start+1:]
+	newline := bytes.IndexByte(rest, '\n')
+	quote := bytes.IndexByte(rest, '"')
+	if newline < 0 || quote < 0 || newline > quote {
+		return ""
+	}
+	return string(rest[newline+1 : quote])
+}
@@ -0,0 +1,55 @@
+package interchange
+
+import (
+	"bytes"
+	"htpatcher/internal/domain"
+	"testing"
+)
+
+// TestRoundTrip checks that exporting a dictionary and importing it again gives the same dictionary,
+// including entries translated to an empty string, and leaves out the texts that were never translated
+func TestRoundTrip(t *testing.T) {
+	original := []byte(`{
+  "Hello": "Bonjour",
+  "Silence": "",
+  "Line\nbreak": "Saut\nde ligne",
+  "\\C[2]Potion": "\\C[2]Potion & <soin>"
+}
+`)
+	dictionary, err := ReadDictionary(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := []domain.SourceText{
+		{Text: "Hello", File: "Map001.json", Location: "Event 1", Speaker: "Harold"},
+		{Text: "Silence", File: "Map001.json", Location: "Event 2"},
+		{Text: "Untranslated", File: "Map002.json", Location: "Event 1"},
+	}
+	units := BuildUnits(dictionary, sources)
+
+	for _, format := range []string{
+		domain.InterchangeFormatPO,
+		domain.InterchangeFormatXLIFF12,
+		domain.InterchangeFormatXLIFF20,
+		domain.InterchangeFormatCSV,
+		domain.InterchangeFormatTSV,
+	} {
+		t.Run(format, func(t *testing.T) {
+			var exported bytes.Buffer
+			if err := Export(&exported, format, units, Metadata{Original: "patch.zip", SourceLanguage: "en", TargetLanguage: "fr"}); err != nil {
+				t.Fatalf("export: %v", err)
+			}
+			imported, err := Import(bytes.NewReader(exported.Bytes()), format)
+			if err != nil {
+				t.Fatalf("import: %v", err)
+			}
+			encoded, err := EncodeDictionary(BuildDictionary(imported, dictionary), original)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if !bytes.Equal(encoded, original) {
+				t.Errorf("round trip changed the dictionary\nexpected: %s\nactual:   %s\nexported: %s", original, encoded, exported.Bytes())
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package interchange
+
+import (
+	"bytes"
+	"fmt"
+	"htpatcher/internal/domain"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Metadata describes the dictionary being exported
+type Metadata struct {
+	Original       string // Name of the patch the dictionary comes from
+	SourceLanguage string
+	TargetLanguage string
+}
+
+// Export writes translation units in the given format
+func Export(w io.Writer, format string, units []domain.TranslationUnit, meta Metadata) error {
+	switch format {
+	case domain.InterchangeFormatPO:
+		return writePO(w, units, meta)
+	case domain.InterchangeFormatXLIFF12:
+		return writeXLIFF12(w, units, meta)
+	case domain.InterchangeFormatXLIFF20:
+		return writeXLIFF20(w, units, meta)
+	case domain.InterchangeFormatCSV:
+		return writeCSV(w, units, ',')
+	case domain.InterchangeFormatTSV:
+		return writeCSV(w, units, '\t')
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// Import reads translation units in the given format
+func Import(r io.Reader, format string) ([]domain.TranslationUnit, error) {
+	switch format {
+	case domain.InterchangeFormatPO:
+		return readPO(r)
+	case domain.InterchangeFormatXLIFF12:
+		return readXLIFF12(r)
+	case domain.InterchangeFormatXLIFF20:
+		return readXLIFF20(r)
+	case domain.InterchangeFormatCSV:
+		return readCSV(r, ',')
+	case domain.InterchangeFormatTSV:
+		return readCSV(r, '\t')
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// Extension returns the file extension used for a format
+func Extension(format string) string {
+	switch format {
+	case domain.InterchangeFormatXLIFF12, domain.InterchangeFormatXLIFF20:
+		return ".xlf"
+	default:
+		return "." + format
+	}
+}
+
+// DetectFormat guesses the format of a file from its extension, and its content for XLIFF
+func DetectFormat(path string, data []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".po", ".pot":
+		return domain.InterchangeFormatPO, nil
+	case ".csv":
+		return domain.InterchangeFormatCSV, nil
+	case ".tsv":
+		return domain.InterchangeFormatTSV, nil
+	case ".xlf", ".xliff":
+		if bytes.Contains(data, []byte("urn:oasis:names:tc:xliff:document:2.")) {
+			return domain.InterchangeFormatXLIFF20, nil
+		}
+		return domain.InterchangeFormatXLIFF12, nil
+	default:
+		return "", fmt.Errorf("unsupported file type %q", filepath.Ext(path))
+	}
+}
@@ -0,0 +1,201 @@
+package interchange
+
+import (
+	"bufio"
+	"fmt"
+	"htpatcher/internal/domain"
+	"io"
+	"strings"
+)
+
+// writePO writes units as a gettext PO file.
+// The dictionary key is stored in msgctxt so it survives the round-trip unchanged,
+// and machine translations are flagged fuzzy so CAT tools ask for a review.
+func writePO(w io.Writer, units []domain.TranslationUnit, meta Metadata) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "# Translation of %s\n", meta.Original)
+	out.WriteString("msgid \"\"\n")
+	out.WriteString("msgstr \"\"\n")
+	out.WriteString("\"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+	out.WriteString("\"Content-Transfer-Encoding: 8bit\\n\"\n")
+	if meta.TargetLanguage != "" {
+		fmt.Fprintf(out, "\"Language: %s\\n\"\n", meta.TargetLanguage)
+	}
+	out.WriteString("\"X-Generator: htpatcher\\n\"\n")
+
+	for _, unit := range units {
+		out.WriteString("\n")
+		for _, speaker := range unit.Speakers {
+			fmt.Fprintf(out, "#. Speaker: %s\n", poComment(speaker))
+		}
+		for _, reference := range unit.References {
+			fmt.Fprintf(out, "#. %s\n", poComment(reference))
+		}
+		if unit.MachineTranslated {
+			out.WriteString("#, fuzzy\n")
+		}
+		writePOString(out, "msgctxt", unit.Key)
+		writePOString(out, "msgid", unit.Source)
+		writePOString(out, "msgstr", unit.Target)
+	}
+
+	return out.Flush()
+}
+
+// writePOString writes a keyword and its string, split after each newline
+func writePOString(out *bufio.Writer, keyword string, value string) {
+	lines := strings.SplitAfter(value, "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 1 {
+		fmt.Fprintf(out, "%s \"%s\"\n", keyword, poEscape(value))
+		return
+	}
+	fmt.Fprintf(out, "%s \"\"\n", keyword)
+	for _, line := range lines {
+		fmt.Fprintf(out, "\"%s\"\n", poEscape(line))
+	}
+}
+
+// readPO reads units from a gettext PO file
+func readPO(r io.Reader) ([]domain.TranslationUnit, error) {
+	units := []domain.TranslationUnit{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	var context, msgid, msgstr *string
+	var current *string
+	fuzzy := false
+	flush := func() {
+		// The header entry has an empty msgid and no context
+		if msgid != nil && (*msgid != "" || context != nil) {
+			unit := domain.TranslationUnit{Source: *msgid, MachineTranslated: fuzzy}
+			if context != nil {
+				unit.Key = *context
+			}
+			if msgstr != nil {
+				unit.Target = *msgstr
+			}
+			units = append(units, unit)
+		}
+		context, msgid, msgstr, current = nil, nil, nil, nil
+		fuzzy = false
+	}
+
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if line == "" || strings.HasPrefix(line, "#") {
+			// A blank line or a comment after a complete entry starts a new one
+			if msgstr != nil {
+				flush()
+			}
+			if strings.HasPrefix(line, "#,") && strings.Contains(line, "fuzzy") {
+				fuzzy = true
+			}
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		if strings.HasPrefix(line, "\"") {
+			if current == nil {
+				return nil, fmt.Errorf("line %d: string without keyword", lineNumber)
+			}
+			value, err := poUnquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			*current += value
+			continue
+		}
+
+		value, err := poUnquote(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		switch {
+		case keyword == "msgctxt":
+			if msgstr != nil {
+				flush()
+			}
+			context = &value
+			current = context
+		case keyword == "msgid":
+			if msgstr != nil {
+				flush()
+			}
+			msgid = &value
+			current = msgid
+		case keyword == "msgstr" || keyword == "msgstr[0]":
+			msgstr = &value
+			current = msgstr
+		default:
+			// Plural forms other than the first are not used by the dictionary
+			discard := value
+			current = &discard
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return units, nil
+}
+
+// poComment keeps a comment on a single line
+func poComment(text string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(text)
+}
+
+// poEscape escapes a string for a PO file
+func poEscape(s string) string {
+	return strings.NewReplacer(
+		"\\", "\\\\",
+		"\"", "\\\"",
+		"\n", "\\n",
+		"\r", "\\r",
+		"\t", "\\t",
+	).Replace(s)
+}
+
+// poUnquote parses a quoted PO string
+func poUnquote(s string) (string, error) {
+	if len(s) < 2 || !strings.HasPrefix(s, "\"") || !strings.HasSuffix(s, "\"") {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+	s = s[1 : len(s)-1]
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", fmt.Errorf("invalid escape at end of string")
+		}
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,242 @@
+package interchange
+
+import (
+	"encoding/xml"
+	"fmt"
+	"htpatcher/internal/domain"
+	"io"
+	"strconv"
+)
+
+const (
+	xliff12Namespace = "urn:oasis:names:tc:xliff:document:1.2"
+	xliff20Namespace = "urn:oasis:names:tc:xliff:document:2.0"
+)
+
+// xliffText is element content with whitespace preserved
+type xliffText struct {
+	Space string `xml:"http://www.w3.org/XML/1998/namespace space,attr,omitempty"`
+	Text  string `xml:",chardata"`
+}
+
+func preservedText(text string) *xliffText {
+	return &xliffText{Space: "preserve", Text: text}
+}
+
+// Machine translations are marked for review with these states
+const (
+	xliff12MachineState     = "needs-review-translation"
+	xliff12MachineQualifier = "mt-suggestion"
+	xliff20MachineState     = "translated"
+	xliff20MachineSubState  = "htpatcher:mt"
+)
+
+// XLIFF 1.2 document. The dictionary key is stored in the resname attribute.
+type xliff12Document struct {
+	XMLName xml.Name      `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+	Version string        `xml:"version,attr"`
+	Files   []xliff12File `xml:"file"`
+}
+
+type xliff12File struct {
+	Original       string        `xml:"original,attr"`
+	SourceLanguage string        `xml:"source-language,attr"`
+	TargetLanguage string        `xml:"target-language,attr,omitempty"`
+	Datatype       string        `xml:"datatype,attr"`
+	Units          []xliff12Unit `xml:"body>trans-unit"`
+}
+
+type xliff12Unit struct {
+	ID      string         `xml:"id,attr"`
+	Resname string         `xml:"resname,attr,omitempty"`
+	Source  xliffText      `xml:"source"`
+	Target  *xliff12Target `xml:"target"`
+	Notes   []xliff12Note  `xml:"note"`
+}
+
+type xliff12Target struct {
+	Space          string `xml:"http://www.w3.org/XML/1998/namespace space,attr,omitempty"`
+	State          string `xml:"state,attr,omitempty"`
+	StateQualifier string `xml:"state-qualifier,attr,omitempty"`
+	Text           string `xml:",chardata"`
+}
+
+type xliff12Note struct {
+	From string `xml:"from,attr,omitempty"`
+	Text string `xml:",chardata"`
+}
+
+// XLIFF 2.0 document. The dictionary key is stored in the name attribute of the unit.
+type xliff20Document struct {
+	XMLName        xml.Name      `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version        string        `xml:"version,attr"`
+	SourceLanguage string        `xml:"srcLang,attr"`
+	TargetLanguage string        `xml:"trgLang,attr,omitempty"`
+	Files          []xliff20File `xml:"file"`
+}
+
+type xliff20File struct {
+	ID       string        `xml:"id,attr"`
+	Original string        `xml:"original,attr,omitempty"`
+	Units    []xliff20Unit `xml:"unit"`
+}
+
+type xliff20Unit struct {
+	ID       string           `xml:"id,attr"`
+	Name     string           `xml:"name,attr,omitempty"`
+	Notes    *xliff20Notes    `xml:"notes"`
+	Segments []xliff20Segment `xml:"segment"`
+}
+
+type xliff20Notes struct {
+	Notes []xliff20Note `xml:"note"`
+}
+
+type xliff20Note struct {
+	Category string `xml:"category,attr,omitempty"`
+	Text     string `xml:",chardata"`
+}
+
+type xliff20Segment struct {
+	State    string     `xml:"state,attr,omitempty"`
+	SubState string     `xml:"subState,attr,omitempty"`
+	Source   xliffText  `xml:"source"`
+	Target   *xliffText `xml:"target"`
+}
+
+// writeXLIFF12 writes units as an XLIFF 1.2 document
+func writeXLIFF12(w io.Writer, units []domain.TranslationUnit, meta Metadata) error {
+	file := xliff12File{
+		Original:       meta.Original,
+		SourceLanguage: meta.SourceLanguage,
+		TargetLanguage: meta.TargetLanguage,
+		Datatype:       "plaintext",
+	}
+	for i, unit := range units {
+		xu := xliff12Unit{
+			ID:      strconv.Itoa(i + 1),
+			Resname: unit.Key,
+			Source:  *preservedText(unit.Source),
+		}
+		if unit.Target != "" {
+			xu.Target = &xliff12Target{Space: "preserve", Text: unit.Target}
+			if unit.MachineTranslated {
+				xu.Target.State = xliff12MachineState
+				xu.Target.StateQualifier = xliff12MachineQualifier
+			}
+		}
+		for _, speaker := range unit.Speakers {
+			xu.Notes = append(xu.Notes, xliff12Note{From: "speaker", Text: speaker})
+		}
+		for _, reference := range unit.References {
+			xu.Notes = append(xu.Notes, xliff12Note{From: "location", Text: reference})
+		}
+		file.Units = append(file.Units, xu)
+	}
+	return writeXML(w, xliff12Document{Version: "1.2", Files: []xliff12File{file}})
+}
+
+// readXLIFF12 reads units from an XLIFF 1.2 document
+func readXLIFF12(r io.Reader) ([]domain.TranslationUnit, error) {
+	var document xliff12Document
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, err
+	}
+	if document.XMLName.Space != xliff12Namespace {
+		return nil, fmt.Errorf("not an XLIFF 1.2 document")
+	}
+
+	units := []domain.TranslationUnit{}
+	for _, file := range document.Files {
+		for _, xu := range file.Units {
+			unit := domain.TranslationUnit{Key: xu.Resname, Source: xu.Source.Text}
+			if xu.Target != nil {
+				unit.Target = xu.Target.Text
+				unit.MachineTranslated = xu.Target.StateQualifier == xliff12MachineQualifier
+			}
+			units = append(units, unit)
+		}
+	}
+	return units, nil
+}
+
+// writeXLIFF20 writes units as an XLIFF 2.0 document
+func writeXLIFF20(w io.Writer, units []domain.TranslationUnit, meta Metadata) error {
+	file := xliff20File{ID: "f1", Original: meta.Original}
+	for i, unit := range units {
+		xu := xliff20Unit{
+			ID:   "u" + strconv.Itoa(i+1),
+			Name: unit.Key,
+		}
+		// A notes element must hold at least one note
+		notes := []xliff20Note{}
+		for _, speaker := range unit.Speakers {
+			notes = append(notes, xliff20Note{Category: "speaker", Text: speaker})
+		}
+		for _, reference := range unit.References {
+			notes = append(notes, xliff20Note{Category: "location", Text: reference})
+		}
+		if len(notes) > 0 {
+			xu.Notes = &xliff20Notes{Notes: notes}
+		}
+		segment := xliff20Segment{Source: *preservedText(unit.Source)}
+		if unit.Target != "" {
+			segment.Target = preservedText(unit.Target)
+			if unit.MachineTranslated {
+				segment.State = xliff20MachineState
+				segment.SubState = xliff20MachineSubState
+			}
+		}
+		xu.Segments = []xliff20Segment{segment}
+		file.Units = append(file.Units, xu)
+	}
+	return writeXML(w, xliff20Document{
+		Version:        "2.0",
+		SourceLanguage: meta.SourceLanguage,
+		TargetLanguage: meta.TargetLanguage,
+		Files:          []xliff20File{file},
+	})
+}
+
+// readXLIFF20 reads units from an XLIFF 2.0 document, joining the segments of each unit
+func readXLIFF20(r io.Reader) ([]domain.TranslationUnit, error) {
+	var document xliff20Document
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, err
+	}
+	if document.XMLName.Space != xliff20Namespace {
+		return nil, fmt.Errorf("not an XLIFF 2.0 document")
+	}
+
+	units := []domain.TranslationUnit{}
+	for _, file := range document.Files {
+		for _, xu := range file.Units {
+			unit := domain.TranslationUnit{Key: xu.Name}
+			for _, segment := range xu.Segments {
+				unit.Source += segment.Source.Text
+				if segment.Target != nil {
+					unit.Target += segment.Target.Text
+				}
+				if segment.SubState == xliff20MachineSubState {
+					unit.MachineTranslated = true
+				}
+			}
+			units = append(units, unit)
+		}
+	}
+	return units, nil
+}
+
+// writeXML writes an indented XML document with its declaration
+func writeXML(w io.Writer, document any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
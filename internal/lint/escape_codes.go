@@ -0,0 +1,92 @@
+package lint
+
+import (
+	"fmt"
+	"htpatcher/internal/domain"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// escapeCodeRegex matches the escape codes that take an argument, e.g. \C[2], \I[64], \N[1] and \V[3]
+var escapeCodeRegex = regexp.MustCompile(`(?i)\\([CINV])\[(\d+)\]`)
+
+// mzEscapeCodeRegex also matches the codes MZ added: \PX[n], \PY[n] and \FS[n]
+var mzEscapeCodeRegex = regexp.MustCompile(`(?i)\\(PX|PY|FS|[CINV])\[(\d+)\]`)
+
+// CheckEscapeCodes flags translations that drop, add or mangle escape codes of their source,
+// or change the balance of the \{ and \} text size codes. The MZ codes are checked unless the engine is MV.
+func CheckEscapeCodes(dictionary map[string]string, engine string) []domain.DictionaryIssue {
+	regex := mzEscapeCodeRegex
+	if engine == domain.EngineMV {
+		regex = escapeCodeRegex
+	}
+	issues := []domain.DictionaryIssue{}
+	for _, key := range sortedKeys(dictionary) {
+		translation := dictionary[key]
+		if translation == "" {
+			continue
+		}
+
+		missing, extra := diffEscapeCodes(escapeCodes(regex, key), escapeCodes(regex, translation))
+		if len(missing) > 0 {
+			issues = append(issues, domain.DictionaryIssue{
+				Key:         key,
+				Translation: translation,
+				Rule:        domain.IssueRuleEscapeCodes,
+				Message:     "Missing escape codes " + strings.Join(missing, " "),
+			})
+		}
+		if len(extra) > 0 {
+			issues = append(issues, domain.DictionaryIssue{
+				Key:         key,
+				Translation: translation,
+				Rule:        domain.IssueRuleEscapeCodes,
+				Message:     "Unexpected escape codes " + strings.Join(extra, " "),
+			})
+		}
+
+		if sourceBalance, translationBalance := sizeCodeBalance(key), sizeCodeBalance(translation); sourceBalance != translationBalance {
+			issues = append(issues, domain.DictionaryIssue{
+				Key:         key,
+				Translation: translation,
+				Rule:        domain.IssueRuleEscapeCodes,
+				Message:     fmt.Sprintf("Unbalanced \\{ and \\}: %+d in the source, %+d in the translation", sourceBalance, translationBalance),
+			})
+		}
+	}
+	return issues
+}
+
+// escapeCodes counts the escape codes of a text. Codes are case insensitive in game, and so are dictionary keys.
+func escapeCodes(regex *regexp.Regexp, text string) map[string]int {
+	codes := make(map[string]int)
+	for _, match := range regex.FindAllStringSubmatch(text, -1) {
+		codes[fmt.Sprintf("\\%s[%s]", strings.ToUpper(match[1]), match[2])]++
+	}
+	return codes
+}
+
+// diffEscapeCodes returns the codes of the source missing from the translation, and the ones the translation added
+func diffEscapeCodes(source map[string]int, translation map[string]int) ([]string, []string) {
+	missing := []string{}
+	extra := []string{}
+	for code, count := range source {
+		for range count - translation[code] {
+			missing = append(missing, code)
+		}
+	}
+	for code, count := range translation {
+		for range count - source[code] {
+			extra = append(extra, code)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	return missing, extra
+}
+
+// sizeCodeBalance returns how many more \{ than \} a text has
+func sizeCodeBalance(text string) int {
+	return strings.Count(text, "\\{") - strings.Count(text, "\\}")
+}
@@ -0,0 +1,78 @@
+package lint
+
+import (
+	"fmt"
+	"htpatcher/internal/domain"
+	"htpatcher/internal/util"
+	"sort"
+	"strings"
+)
+
+// CheckGlossary flags dictionary entries whose source contains a glossary term
+// but whose translation does not use the term's required translation
+func CheckGlossary(dictionary map[string]string, glossary map[string]string) []domain.DictionaryIssue {
+	terms := glossaryTerms(glossary)
+	issues := []domain.DictionaryIssue{}
+	for _, key := range sortedKeys(dictionary) {
+		translation := dictionary[key]
+		if translation == "" {
+			continue
+		}
+
+		lowerTranslation := strings.ToLower(translation)
+		remaining := key
+		for _, term := range terms {
+			if !strings.Contains(remaining, term.key) {
+				continue
+			}
+			// Longer terms are checked first, so a term inside one already matched is not checked again
+			remaining = strings.ReplaceAll(remaining, term.key, "\x00")
+			if strings.Contains(lowerTranslation, strings.ToLower(term.target)) {
+				continue
+			}
+			issues = append(issues, domain.DictionaryIssue{
+				Key:         key,
+				Translation: translation,
+				Rule:        domain.IssueRuleGlossary,
+				Message:     fmt.Sprintf("%q should be translated as %q", term.source, term.target),
+			})
+		}
+	}
+	return issues
+}
+
+// glossaryTerm is a glossary entry with its source normalized like dictionary keys
+type glossaryTerm struct {
+	source string
+	key    string
+	target string
+}
+
+// glossaryTerms returns the usable glossary entries, longest source first
+func glossaryTerms(glossary map[string]string) []glossaryTerm {
+	terms := []glossaryTerm{}
+	for source, target := range glossary {
+		key := util.GetTranslationKey(source)
+		if key == "" || strings.TrimSpace(target) == "" {
+			continue
+		}
+		terms = append(terms, glossaryTerm{source: source, key: key, target: strings.TrimSpace(target)})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if len(terms[i].key) != len(terms[j].key) {
+			return len(terms[i].key) > len(terms[j].key)
+		}
+		return terms[i].key < terms[j].key
+	})
+	return terms
+}
+
+// sortedKeys returns the keys of a dictionary in a stable order
+func sortedKeys(dictionary map[string]string) []string {
+	keys := make([]string, 0, len(dictionary))
+	for key := range dictionary {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,81 @@
+package lint
+
+import (
+	"fmt"
+	"htpatcher/internal/domain"
+	"htpatcher/internal/util"
+	"slices"
+	"strings"
+)
+
+// windowLines is the number of lines the windows showing source texts hold at once
+var windowLines = map[string]int{
+	domain.SourceWindowMessage:     4,
+	domain.SourceWindowMessageFace: 4,
+	domain.SourceWindowHelp:        2,
+}
+
+// windowNames names the windows in issue messages
+var windowNames = map[string]string{
+	domain.SourceWindowMessage:     "message",
+	domain.SourceWindowMessageFace: "message",
+	domain.SourceWindowHelp:        "help",
+}
+
+// dynamicWrapFaceWidth is how much narrower dialogue next to a face is wrapped with a dynamic wrap width
+const dynamicWrapFaceWidth = 10
+
+// CheckLength flags translations that need more lines than the window showing their source holds,
+// once wrapped the way the patch wraps them. Texts shown elsewhere, such as names and choices, are not checked.
+func CheckLength(dictionary map[string]string, sources []domain.SourceText, config *domain.Config) []domain.DictionaryIssue {
+	windows := map[string][]string{}
+	for _, source := range sources {
+		if _, ok := windowLines[source.Window]; !ok {
+			continue
+		}
+		key := util.GetTranslationKey(source.Text)
+		if !slices.Contains(windows[key], source.Window) {
+			windows[key] = append(windows[key], source.Window)
+		}
+	}
+
+	issues := []domain.DictionaryIssue{}
+	for _, key := range sortedKeys(dictionary) {
+		translation := dictionary[key]
+		if translation == "" {
+			continue
+		}
+
+		for _, window := range windows[key] {
+			lines := wrappedLines(translation, window, config)
+			if lines > windowLines[window] {
+				issues = append(issues, domain.DictionaryIssue{
+					Key:         key,
+					Translation: translation,
+					Rule:        domain.IssueRuleLength,
+					Message:     fmt.Sprintf("Needs %d lines, the %s window holds %d", lines, windowNames[window], windowLines[window]),
+				})
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// wrappedLines returns the number of lines of a translation once wrapped for a window
+func wrappedLines(translation string, window string, config *domain.Config) int {
+	var wrapped string
+	switch window {
+	case domain.SourceWindowHelp:
+		wrapped = util.Wrap(util.NoNewline(translation), config.WrapWidth)
+	case domain.SourceWindowMessageFace:
+		width := config.WrapWidth
+		if config.DynamicWrapWidth {
+			width -= dynamicWrapFaceWidth
+		}
+		wrapped = util.Wrap(translation, width)
+	default:
+		wrapped = util.Wrap(translation, config.WrapWidth)
+	}
+	return strings.Count(wrapped, "\n") + 1
+}
@@ -0,0 +1,183 @@
+package patcher
+
+import (
+	"context"
+	"fmt"
+	"htpatcher/internal/domain"
+	"htpatcher/internal/domain/rpgmaker"
+	"htpatcher/internal/util"
+	"slices"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+const commandHookName = "patchCommand"
+
+// CommandHook calls the patchCommand function of a patch on the event commands with a configured code.
+// The function gets the command and a context with its index and neighbours. It returns the command
+// once edited, or a list of commands replacing it, empty to delete it. Returning nothing keeps the command as it was.
+type CommandHook struct {
+	sandbox *luaSandbox
+	api     *luaAPI
+	codes   []int
+	hook    lua.LValue
+}
+
+// NewCommandHook loads the commands patch script of a patch, nil when the patch has none
+func (e *Engine) NewCommandHook(ctx context.Context, patchInfo *domain.PatchInfo) (*CommandHook, error) {
+	config := patchInfo.Config
+	if config == nil || config.CommandsPatchScript == "" || len(config.CommandCodesToPatch) == 0 {
+		return nil, nil
+	}
+
+	sandbox := newLuaSandbox(ctx)
+	sandbox.scriptName = commandHookName
+	hook := &CommandHook{
+		sandbox: sandbox,
+		api:     newLuaAPI(sandbox, e.logger, patchInfo.Dictionary),
+		codes:   config.CommandCodesToPatch,
+	}
+	err := sandbox.run(func() error {
+		env, err := sandbox.load(commandHookName, config.CommandsPatchScript)
+		if err != nil {
+			return err
+		}
+		hook.hook = env.RawGetString(commandHookName)
+		if hook.hook.Type() != lua.LTFunction {
+			return fmt.Errorf("the commands patch script does not define a %s function", commandHookName)
+		}
+		return nil
+	})
+	if err != nil {
+		sandbox.Close()
+		return nil, err
+	}
+	return hook, nil
+}
+
+// Close releases the Lua state of the hook
+func (h *CommandHook) Close() {
+	if h != nil {
+		h.sandbox.Close()
+	}
+}
+
+// patch runs the hook on the commands with a configured code and returns the resulting list
+func (h *CommandHook) patch(commands []*rpgmaker.EventCommand) ([]*rpgmaker.EventCommand, error) {
+	if h == nil {
+		return commands, nil
+	}
+
+	result := make([]*rpgmaker.EventCommand, 0, len(commands))
+	for i, command := range commands {
+		if !slices.Contains(h.codes, command.Code) {
+			result = append(result, command)
+			continue
+		}
+		var replacement []*rpgmaker.EventCommand
+		err := h.sandbox.run(func() error {
+			var err error
+			replacement, err = h.patchCommand(commands, i)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, replacement...)
+	}
+	return result, nil
+}
+
+// patchCommand calls the hook on a command and returns the commands replacing it
+func (h *CommandHook) patchCommand(commands []*rpgmaker.EventCommand, index int) ([]*rpgmaker.EventCommand, error) {
+	L := h.sandbox.L
+	command := commands[index]
+	commandTable := h.toLuaCommand(L, command)
+
+	hookContext := L.NewTable()
+	hookContext.RawSetString("index", lua.LNumber(index+1))
+	if index > 0 {
+		hookContext.RawSetString("previous", h.toLuaCommand(L, commands[index-1]))
+	}
+	if index+1 < len(commands) {
+		hookContext.RawSetString("next", h.toLuaCommand(L, commands[index+1]))
+	}
+
+	result, err := h.sandbox.call(commandHookName, h.hook, commandTable, hookContext)
+	if err != nil {
+		return nil, err
+	}
+
+	switch result := result.(type) {
+	case *lua.LNilType:
+		// Converting back from Lua is lossy, so only returned commands are converted
+		return []*rpgmaker.EventCommand{command}, nil
+	case *lua.LTable:
+		if result.RawGetString("code") != lua.LNil {
+			patched, err := fromLuaCommand(result, command.Indent)
+			if err != nil {
+				return nil, err
+			}
+			patched.Extras = command.Extras
+			return []*rpgmaker.EventCommand{patched}, nil
+		}
+		replacement := []*rpgmaker.EventCommand{}
+		for i := 1; i <= result.Len(); i++ {
+			item, ok := result.RawGetInt(i).(*lua.LTable)
+			if !ok {
+				return nil, fmt.Errorf("%s: returned list item %d is not a command", commandHookName, i)
+			}
+			patched, err := fromLuaCommand(item, command.Indent)
+			if err != nil {
+				return nil, err
+			}
+			replacement = append(replacement, patched)
+		}
+		return replacement, nil
+	default:
+		return nil, fmt.Errorf("%s: returned a %s instead of a command or a list of commands", commandHookName, result.Type())
+	}
+}
+
+// toLuaCommand converts an event command to a table with its code, indent and parameters
+func (h *CommandHook) toLuaCommand(L *lua.LState, command *rpgmaker.EventCommand) *lua.LTable {
+	table := L.NewTable()
+	table.RawSetString("code", lua.LNumber(command.Code))
+	table.RawSetString("indent", lua.LNumber(command.Indent))
+	parameters := command.Parameters
+	if parameters == nil {
+		parameters = []any{}
+	}
+	table.RawSetString("parameters", h.api.toLuaValue(L, parameters, false))
+	return table
+}
+
+// fromLuaCommand converts a command table back to an event command, using defaultIndent when it has no indent
+func fromLuaCommand(table *lua.LTable, defaultIndent int) (*rpgmaker.EventCommand, error) {
+	code, ok := table.RawGetString("code").(lua.LNumber)
+	if !ok {
+		return nil, fmt.Errorf("%s: command code must be a number", commandHookName)
+	}
+	command := &rpgmaker.EventCommand{Code: int(code), Indent: defaultIndent, Parameters: []any{}}
+	if indent, ok := table.RawGetString("indent").(lua.LNumber); ok {
+		command.Indent = int(indent)
+	}
+
+	parameters, err := fromLuaValue(table.RawGetString("parameters"), 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", commandHookName, err)
+	}
+	switch parameters := parameters.(type) {
+	case nil:
+	case []any:
+		command.Parameters = parameters
+	case *util.OrderedMap:
+		// An empty table without a JSON type encodes as an object
+		if len(parameters.Keys) > 0 {
+			return nil, fmt.Errorf("%s: command parameters must be a list", commandHookName)
+		}
+	default:
+		return nil, fmt.Errorf("%s: command parameters must be a list", commandHookName)
+	}
+	return command, nil
+}
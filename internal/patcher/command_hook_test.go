@@ -0,0 +1,78 @@
+package patcher
+
+import (
+	"context"
+	"encoding/json"
+	"htpatcher/internal/domain"
+	"htpatcher/internal/domain/rpgmaker"
+	"strings"
+	"testing"
+)
+
+// runCommandHook runs a commands patch script on 357 plugin commands and returns them encoded
+func runCommandHook(t *testing.T, script string, commands string) string {
+	t.Helper()
+	var list []*rpgmaker.EventCommand
+	if err := json.Unmarshal([]byte(commands), &list); err != nil {
+		t.Fatal(err)
+	}
+	patchInfo := &domain.PatchInfo{
+		Dictionary: map[string]string{"こんにちは": "Hello"},
+		Config:     &domain.Config{CommandsPatchScript: script, CommandCodesToPatch: []int{357}},
+	}
+	hook, err := NewEngine(testLogger{}).NewCommandHook(context.Background(), patchInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hook.Close()
+
+	patched, err := hook.patch(list)
+	if err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	encoded, err := json.Marshal(patched)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(encoded)
+}
+
+// TestCommandHookNoop checks that a hook returning nothing leaves the parameters byte-identical,
+// even when they are nested deeper than Lua tables can be converted back
+func TestCommandHookNoop(t *testing.T) {
+	nested := strings.Repeat("[", luaMaxJSONDepth+1) + strings.Repeat("]", luaMaxJSONDepth+1)
+	commands := `[{"code":357,"indent":1,"parameters":["Plugin","show","",{"text":"こんにちは","ids":[1,null,2],"empty":{},"list":"[1,null]"}]},` +
+		`{"code":357,"indent":0,"parameters":["Plugin",` + nested + `]}]`
+	script := `
+function patchCommand(command, context)
+  local unused = command.parameters[1]
+end`
+	var list []*rpgmaker.EventCommand
+	if err := json.Unmarshal([]byte(commands), &list); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := json.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := runCommandHook(t, script, commands); actual != string(expected) {
+		t.Errorf("no-op hook changed the command\nexpected: %s\nactual:   %s", expected, actual)
+	}
+}
+
+// TestCommandHookEdits checks that the commands returned by a hook replace the command
+func TestCommandHookEdits(t *testing.T) {
+	commands := `[{"code":357,"indent":0,"parameters":["Plugin","show",{"text":"こんにちは"}]},{"code":357,"indent":0,"parameters":["Plugin","hide"]}]`
+	script := `
+function patchCommand(command, context)
+  if command.parameters[2] == "hide" then
+    return {}
+  end
+  command.parameters[3].text = getTranslationByKey(command.parameters[3].text)
+  return {command, {code = 108, parameters = {"translated"}}}
+end`
+	expected := `[{"code":357,"indent":0,"parameters":["Plugin","show",{"text":"Hello"}]},{"code":108,"indent":0,"parameters":["translated"]}]`
+	if actual := runCommandHook(t, script, commands); actual != expected {
+		t.Errorf("hook edits\nexpected: %s\nactual:   %s", expected, actual)
+	}
+}
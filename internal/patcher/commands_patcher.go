@@ -103,8 +103,8 @@ func patchParameterValue(value any, dictionary map[string]string) any {
 	}
 }
 
-// patchCommands patches event commands
-func patchCommands(commands []*rpgmaker.EventCommand, patchInfo *domain.PatchInfo) ([]*rpgmaker.EventCommand, error) {
+// patchCommands patches event commands, then runs the command hook of the patch on them
+func patchCommands(commands []*rpgmaker.EventCommand, patchInfo *domain.PatchInfo, hook *CommandHook) ([]*rpgmaker.EventCommand, error) {
 	commandsToDelete := []int{}
 	commandIndex := 0
 	last101CommandHasSpeakerThumbnail := false
@@ -252,21 +252,25 @@ func patchCommands(commands []*rpgmaker.EventCommand, patchInfo *domain.PatchInf
 				if plugin, ok := command.Parameters[0].(string); ok {
 					if function, ok := command.Parameters[1].(string); ok {
 						for _, parameter := range patchInfo.Config.ParametersToPatch {
-							if parameter.Plugin == plugin && parameter.Function == function {
-								switch parameter.RootType {
-								case "string":
-									if options, ok := command.Parameters[3].(string); ok {
-										if translation, ok := patchInfo.Dictionary[util.GetTranslationKey(options)]; ok {
-											command.Parameters[3] = util.Wrap(translation, patchInfo.Config.WrapWidth)
-										}
-									}
-								case "array":
-									if options, ok := command.Parameters[3].([]any); ok {
-										command.Parameters[3] = patchParameterValue(options, patchInfo.Dictionary)
+							if parameter.Plugin != plugin || parameter.Function != function {
+								continue
+							}
+							switch {
+							case len(parameter.ParameterPathsToPatch) > 0:
+								// Only the texts at the paths are translated
+								command.Parameters[3] = translateParameterPaths(command.Parameters[3], parameter.ParameterPathsToPatch, patchInfo.Dictionary, patchInfo.Config.WrapWidth)
+							case parameter.RootType == "string":
+								if options, ok := command.Parameters[3].(string); ok {
+									if translation, ok := patchInfo.Dictionary[util.GetTranslationKey(options)]; ok {
+										command.Parameters[3] = util.Wrap(translation, patchInfo.Config.WrapWidth)
 									}
-								case "object":
-									command.Parameters[3] = patchParameterValue(command.Parameters[3], patchInfo.Dictionary)
 								}
+							case parameter.Recursive && parameter.RootType == "array":
+								if options, ok := command.Parameters[3].([]any); ok {
+									command.Parameters[3] = patchParameterValue(options, patchInfo.Dictionary)
+								}
+							case parameter.Recursive && parameter.RootType == "object":
+								command.Parameters[3] = patchParameterValue(command.Parameters[3], patchInfo.Dictionary)
 							}
 						}
 					}
@@ -286,5 +290,5 @@ func patchCommands(commands []*rpgmaker.EventCommand, patchInfo *domain.PatchInf
 		newCommands = append(newCommands, command)
 	}
 
-	return newCommands, nil
+	return hook.patch(newCommands)
 }
@@ -0,0 +1,157 @@
+package patcher
+
+import (
+	"errors"
+	"fmt"
+	"htpatcher/internal/domain"
+	"htpatcher/internal/util"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Types of the data fields
+const (
+	DataFieldText      = "text"      // Translated as is, the default
+	DataFieldWrap      = "wrap"      // Translated, joined to one line and wrapped to the wrap width of the patch
+	DataFieldNoNewline = "nonewline" // Translated and joined to one line
+)
+
+// dataFileFields lists the fields translated in a type of data file
+type dataFileFields struct {
+	label  string // Start of the locations of the texts, e.g. "Actor"
+	fields []domain.DataFieldToPatch
+}
+
+// builtinDataFields are the fields translated in the database files, by file type.
+// Maps, common events, troops and the system have their own patchers.
+var builtinDataFields = map[string]dataFileFields{
+	"actors": {"Actor", []domain.DataFieldToPatch{
+		{Path: "$[*].name"},
+		{Path: "$[*].nickname"},
+		{Path: "$[*].profile", Type: DataFieldWrap, Window: domain.SourceWindowHelp},
+	}},
+	"armors": {"Armor", []domain.DataFieldToPatch{
+		{Path: "$[*].name"},
+		{Path: "$[*].description", Type: DataFieldWrap, Window: domain.SourceWindowHelp},
+	}},
+	"classes": {"Class", []domain.DataFieldToPatch{
+		{Path: "$[*].name"},
+		{Path: "$[*].note"},
+	}},
+	"enemies": {"Enemy", []domain.DataFieldToPatch{
+		{Path: "$[*].name"},
+		{Path: "$[*].note"},
+	}},
+	"items": {"Item", []domain.DataFieldToPatch{
+		{Path: "$[*].name"},
+		{Path: "$[*].description", Type: DataFieldWrap, Window: domain.SourceWindowHelp},
+		{Path: "$[*].note", Type: DataFieldNoNewline},
+	}},
+	"skills": {"Skill", []domain.DataFieldToPatch{
+		{Path: "$[*].name"},
+		{Path: "$[*].description", Type: DataFieldWrap, Window: domain.SourceWindowHelp},
+		{Path: "$[*].message1", Type: DataFieldWrap},
+		{Path: "$[*].message2", Type: DataFieldWrap},
+		{Path: "$[*].note"},
+	}},
+	"states": {"State", []domain.DataFieldToPatch{
+		{Path: "$[*].name"},
+		{Path: "$[*].message1", Type: DataFieldWrap},
+		{Path: "$[*].message2", Type: DataFieldWrap},
+		{Path: "$[*].message3", Type: DataFieldWrap},
+		{Path: "$[*].message4", Type: DataFieldWrap},
+	}},
+	"weapons": {"Weapon", []domain.DataFieldToPatch{
+		{Path: "$[*].name"},
+		{Path: "$[*].description", Type: DataFieldWrap, Window: domain.SourceWindowHelp},
+	}},
+	"mapinfos": {"Map", []domain.DataFieldToPatch{
+		{Path: "$[*].name"},
+	}},
+	"animations": {"Animation", []domain.DataFieldToPatch{
+		{Path: "$[*].name"},
+	}},
+	"tilesets": {"Tileset", []domain.DataFieldToPatch{
+		{Path: "$[*].name"},
+		{Path: "$[*].note"},
+	}},
+}
+
+// CheckDataField checks the path, the type and the window of a data field
+func CheckDataField(field domain.DataFieldToPatch) error {
+	if !slices.Contains([]string{"", DataFieldText, DataFieldWrap, DataFieldNoNewline}, field.Type) {
+		return fmt.Errorf("invalid type %s of path %s", field.Type, field.Path)
+	}
+	if !slices.Contains([]string{"", domain.SourceWindowMessage, domain.SourceWindowHelp}, field.Window) {
+		return fmt.Errorf("invalid window %s of path %s", field.Window, field.Path)
+	}
+	return CheckParameterPath(field.Path)
+}
+
+// CheckDataFile checks the name and the fields of a data file to patch
+func CheckDataFile(file domain.DataFileToPatch) error {
+	if file.File == "" || strings.ContainsAny(file.File, `/\`) {
+		return errors.New("file must be the name of a file of the data folder")
+	}
+	for _, field := range file.Fields {
+		if err := CheckDataField(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dataFieldsOf returns the fields to translate in a data file: the built-in ones of its type,
+// unless it is an optional file the patch does not opt in to, and the ones the patch config adds
+func dataFieldsOf(filePath string, config *domain.Config) dataFileFields {
+	fileType := getDataFileTypeMap(filePath)
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+
+	result := dataFileFields{label: name}
+	if builtin, ok := builtinDataFields[fileType]; ok {
+		result.label = builtin.label
+		if !slices.Contains(OptionalDataFiles, fileType) || slices.Contains(config.DataFilesToPatch, fileType) {
+			result.fields = slices.Clone(builtin.fields)
+		}
+	}
+	for _, file := range config.DataFieldsToPatch {
+		if strings.EqualFold(strings.TrimSuffix(file.File, ".json"), name) {
+			result.fields = append(result.fields, file.Fields...)
+		}
+	}
+	return result
+}
+
+// patchDataFields translates the fields of a data file, keeping the order of its keys
+func patchDataFields(data []byte, fields []domain.DataFieldToPatch, patchInfo *domain.PatchInfo) ([]byte, error) {
+	value, err := util.ParseJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range fields {
+		steps, err := parseParameterPath(field.Path)
+		if err != nil {
+			continue // Checked when the patch is loaded
+		}
+		value = visitParameterPath(value, steps, func(text string) string {
+			return translateDataField(text, field.Type, patchInfo.Dictionary, patchInfo.Config.WrapWidth)
+		})
+	}
+	return util.MarshalUnescaped(value)
+}
+
+// translateDataField returns the translation of a text formatted for the type of its field, or the text without one
+func translateDataField(text string, fieldType string, dictionary map[string]string, wrapWidth int) string {
+	translation, ok := dictionary[util.GetTranslationKey(text)]
+	if !ok {
+		return text
+	}
+	switch fieldType {
+	case DataFieldWrap:
+		return util.Wrap(util.NoNewline(translation), wrapWidth)
+	case DataFieldNoNewline:
+		return util.NoNewline(translation)
+	}
+	return translation
+}
@@ -7,74 +7,8 @@ import (
 	"htpatcher/internal/util"
 )
 
-// patchActors patches actor data
-func patchActors(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
-	var actors rpgmaker.ActorsData
-	if err := json.Unmarshal(data, &actors); err != nil {
-		return nil, err
-	}
-
-	for _, actor := range actors {
-		if actor == nil {
-			continue
-		}
-		if name, ok := patchInfo.Dictionary[util.GetTranslationKey(actor.Name)]; ok {
-			actor.Name = name
-		}
-		if profile, ok := patchInfo.Dictionary[util.GetTranslationKey(actor.Profile)]; ok {
-			actor.Profile = util.Wrap(util.NoNewline(profile), patchInfo.Config.WrapWidth)
-		}
-	}
-
-	return json.Marshal(actors)
-}
-
-// patchArmors patches armor data
-func patchArmors(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
-	var armors rpgmaker.ArmorsData
-	if err := json.Unmarshal(data, &armors); err != nil {
-		return nil, err
-	}
-
-	for _, armor := range armors {
-		if armor == nil {
-			continue
-		}
-		if name, ok := patchInfo.Dictionary[util.GetTranslationKey(armor.Name)]; ok {
-			armor.Name = name
-		}
-		if description, ok := patchInfo.Dictionary[util.GetTranslationKey(armor.Description)]; ok {
-			armor.Description = util.Wrap(util.NoNewline(description), patchInfo.Config.WrapWidth)
-		}
-	}
-
-	return json.Marshal(armors)
-}
-
-// patchClasses patches class data
-func patchClasses(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
-	var classes rpgmaker.ClassesData
-	if err := json.Unmarshal(data, &classes); err != nil {
-		return nil, err
-	}
-
-	for _, class := range classes {
-		if class == nil {
-			continue
-		}
-		if name, ok := patchInfo.Dictionary[util.GetTranslationKey(class.Name)]; ok {
-			class.Name = name
-		}
-		if note, ok := patchInfo.Dictionary[util.GetTranslationKey(class.Note)]; ok {
-			class.Note = note
-		}
-	}
-
-	return json.Marshal(classes)
-}
-
 // patchCommonEvents patches common event data
-func patchCommonEvents(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
+func patchCommonEvents(data []byte, patchInfo *domain.PatchInfo, hook *CommandHook) ([]byte, error) {
 	var commonEvents rpgmaker.CommonEventsData
 	if err := json.Unmarshal(data, &commonEvents); err != nil {
 		return nil, err
@@ -84,7 +18,7 @@ func patchCommonEvents(data []byte, patchInfo *domain.PatchInfo) ([]byte, error)
 		if commonEvent == nil {
 			continue
 		}
-		newCommands, err := patchCommands(commonEvent.List, patchInfo)
+		newCommands, err := patchCommands(commonEvent.List, patchInfo, hook)
 		if err != nil {
 			return nil, err
 		}
@@ -94,55 +28,8 @@ func patchCommonEvents(data []byte, patchInfo *domain.PatchInfo) ([]byte, error)
 	return json.Marshal(commonEvents)
 }
 
-// patchEnemies patches enemy data
-func patchEnemies(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
-	var enemies rpgmaker.EnemiesData
-	if err := json.Unmarshal(data, &enemies); err != nil {
-		return nil, err
-	}
-
-	for _, enemy := range enemies {
-		if enemy == nil {
-			continue
-		}
-		if name, ok := patchInfo.Dictionary[util.GetTranslationKey(enemy.Name)]; ok {
-			enemy.Name = name
-		}
-		if note, ok := patchInfo.Dictionary[util.GetTranslationKey(enemy.Note)]; ok {
-			enemy.Note = note
-		}
-	}
-
-	return json.Marshal(enemies)
-}
-
-// patchItems patches item data
-func patchItems(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
-	var items rpgmaker.ItemsData
-	if err := json.Unmarshal(data, &items); err != nil {
-		return nil, err
-	}
-
-	for _, item := range items {
-		if item == nil {
-			continue
-		}
-		if name, ok := patchInfo.Dictionary[util.GetTranslationKey(item.Name)]; ok {
-			item.Name = name
-		}
-		if description, ok := patchInfo.Dictionary[util.GetTranslationKey(item.Description)]; ok {
-			item.Description = util.Wrap(util.NoNewline(description), patchInfo.Config.WrapWidth)
-		}
-		if note, ok := patchInfo.Dictionary[util.GetTranslationKey(item.Note)]; ok {
-			item.Note = util.NoNewline(note)
-		}
-	}
-
-	return json.Marshal(items)
-}
-
 // patchMap patches map data
-func patchMap(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
+func patchMap(data []byte, patchInfo *domain.PatchInfo, hook *CommandHook) ([]byte, error) {
 	var mapData rpgmaker.MapData
 	if err := json.Unmarshal(data, &mapData); err != nil {
 		return nil, err
@@ -157,7 +44,7 @@ func patchMap(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
 			continue
 		}
 		for i := range event.Pages {
-			newCommands, err := patchCommands(event.Pages[i].List, patchInfo)
+			newCommands, err := patchCommands(event.Pages[i].List, patchInfo, hook)
 			if err != nil {
 				return nil, err
 			}
@@ -168,67 +55,8 @@ func patchMap(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
 	return json.Marshal(mapData)
 }
 
-// patchSkills patches skill data
-func patchSkills(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
-	var skills rpgmaker.SkillsData
-	if err := json.Unmarshal(data, &skills); err != nil {
-		return nil, err
-	}
-
-	for _, skill := range skills {
-		if skill == nil {
-			continue
-		}
-		if name, ok := patchInfo.Dictionary[util.GetTranslationKey(skill.Name)]; ok {
-			skill.Name = name
-		}
-		if description, ok := patchInfo.Dictionary[util.GetTranslationKey(skill.Description)]; ok {
-			skill.Description = util.Wrap(util.NoNewline(description), patchInfo.Config.WrapWidth)
-		}
-		if message1, ok := patchInfo.Dictionary[util.GetTranslationKey(skill.Message1)]; ok {
-			skill.Message1 = util.Wrap(util.NoNewline(message1), patchInfo.Config.WrapWidth)
-		}
-		if message2, ok := patchInfo.Dictionary[util.GetTranslationKey(skill.Message2)]; ok {
-			skill.Message2 = util.Wrap(util.NoNewline(message2), patchInfo.Config.WrapWidth)
-		}
-	}
-
-	return json.Marshal(skills)
-}
-
-// patchStates patches state data
-func patchStates(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
-	var states rpgmaker.StatesData
-	if err := json.Unmarshal(data, &states); err != nil {
-		return nil, err
-	}
-
-	for _, state := range states {
-		if state == nil {
-			continue
-		}
-		if name, ok := patchInfo.Dictionary[util.GetTranslationKey(state.Name)]; ok {
-			state.Name = name
-		}
-		if message1, ok := patchInfo.Dictionary[util.GetTranslationKey(state.Message1)]; ok {
-			state.Message1 = util.Wrap(util.NoNewline(message1), patchInfo.Config.WrapWidth)
-		}
-		if message2, ok := patchInfo.Dictionary[util.GetTranslationKey(state.Message2)]; ok {
-			state.Message2 = util.Wrap(util.NoNewline(message2), patchInfo.Config.WrapWidth)
-		}
-		if message3, ok := patchInfo.Dictionary[util.GetTranslationKey(state.Message3)]; ok {
-			state.Message3 = util.Wrap(util.NoNewline(message3), patchInfo.Config.WrapWidth)
-		}
-		if message4, ok := patchInfo.Dictionary[util.GetTranslationKey(state.Message4)]; ok {
-			state.Message4 = util.Wrap(util.NoNewline(message4), patchInfo.Config.WrapWidth)
-		}
-	}
-
-	return json.Marshal(states)
-}
-
 // patchSystem patches system data
-func patchSystem(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
+func patchSystem(data []byte, patchInfo *domain.PatchInfo, engine string) ([]byte, error) {
 	var system rpgmaker.System
 	if err := json.Unmarshal(data, &system); err != nil {
 		return nil, err
@@ -239,6 +67,11 @@ func patchSystem(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
 		system.Locale = patchInfo.Config.Locale
 	}
 
+	// MZ keeps its fonts in System.json, MV in css/game.css
+	if engine == domain.EngineMZ && system.Advanced != nil && patchInfo.Config != nil && patchInfo.Config.Fonts != nil {
+		patchFonts(system.Advanced, patchInfo.Config.Fonts)
+	}
+
 	// Patch armor types
 	for i := range system.ArmorTypes {
 		if translation, ok := patchInfo.Dictionary[util.GetTranslationKey(system.ArmorTypes[i])]; ok {
@@ -314,7 +147,7 @@ func patchSystem(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
 	// Patch all term messages
 	patchTermMessage(&system.Terms.Messages.AlwaysDash, patchInfo.Dictionary)
 	patchTermMessage(&system.Terms.Messages.CommandRemember, patchInfo.Dictionary)
-	patchTermMessage(&system.Terms.Messages.TouchUI, patchInfo.Dictionary)
+	patchOptionalTermMessage(system.Terms.Messages.TouchUI, patchInfo.Dictionary)
 	patchTermMessage(&system.Terms.Messages.BgmVolume, patchInfo.Dictionary)
 	patchTermMessage(&system.Terms.Messages.BgsVolume, patchInfo.Dictionary)
 	patchTermMessage(&system.Terms.Messages.MeVolume, patchInfo.Dictionary)
@@ -325,7 +158,7 @@ func patchSystem(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
 	patchTermMessage(&system.Terms.Messages.SaveMessage, patchInfo.Dictionary)
 	patchTermMessage(&system.Terms.Messages.LoadMessage, patchInfo.Dictionary)
 	patchTermMessage(&system.Terms.Messages.File, patchInfo.Dictionary)
-	patchTermMessage(&system.Terms.Messages.Autosave, patchInfo.Dictionary)
+	patchOptionalTermMessage(system.Terms.Messages.Autosave, patchInfo.Dictionary)
 	patchTermMessage(&system.Terms.Messages.PartyName, patchInfo.Dictionary)
 	patchTermMessage(&system.Terms.Messages.Emerge, patchInfo.Dictionary)
 	patchTermMessage(&system.Terms.Messages.Preemptive, patchInfo.Dictionary)
@@ -376,8 +209,31 @@ func patchTermMessage(message *string, dictionary map[string]string) {
 	}
 }
 
+// patchOptionalTermMessage patches a term message only some engines have
+func patchOptionalTermMessage(message *string, dictionary map[string]string) {
+	if message != nil {
+		patchTermMessage(message, dictionary)
+	}
+}
+
+// patchFonts replaces the fonts of an MZ game with the ones set in the patch config
+func patchFonts(advanced *rpgmaker.Advanced, fonts *domain.FontSettings) {
+	if fonts.MainFontFilename != "" {
+		advanced.MainFontFilename = fonts.MainFontFilename
+	}
+	if fonts.NumberFontFilename != "" {
+		advanced.NumberFontFilename = fonts.NumberFontFilename
+	}
+	if fonts.FallbackFonts != "" {
+		advanced.FallbackFonts = fonts.FallbackFonts
+	}
+	if fonts.FontSize > 0 {
+		advanced.FontSize = fonts.FontSize
+	}
+}
+
 // patchTroops patches troop data
-func patchTroops(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
+func patchTroops(data []byte, patchInfo *domain.PatchInfo, hook *CommandHook) ([]byte, error) {
 	var troops rpgmaker.TroopsData
 	if err := json.Unmarshal(data, &troops); err != nil {
 		return nil, err
@@ -391,7 +247,7 @@ func patchTroops(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
 			troop.Name = name
 		}
 		for i := range troop.Pages {
-			newCommands, err := patchCommands(troop.Pages[i].List, patchInfo)
+			newCommands, err := patchCommands(troop.Pages[i].List, patchInfo, hook)
 			if err != nil {
 				return nil, err
 			}
@@ -401,25 +257,3 @@ func patchTroops(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
 
 	return json.Marshal(troops)
 }
-
-// patchWeapons patches weapon data
-func patchWeapons(data []byte, patchInfo *domain.PatchInfo) ([]byte, error) {
-	var weapons rpgmaker.WeaponsData
-	if err := json.Unmarshal(data, &weapons); err != nil {
-		return nil, err
-	}
-
-	for _, weapon := range weapons {
-		if weapon == nil {
-			continue
-		}
-		if name, ok := patchInfo.Dictionary[util.GetTranslationKey(weapon.Name)]; ok {
-			weapon.Name = name
-		}
-		if description, ok := patchInfo.Dictionary[util.GetTranslationKey(weapon.Description)]; ok {
-			weapon.Description = util.Wrap(util.NoNewline(description), patchInfo.Config.WrapWidth)
-		}
-	}
-
-	return json.Marshal(weapons)
-}
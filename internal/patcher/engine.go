@@ -2,10 +2,13 @@ package patcher
 
 import (
 	"context"
+	"fmt"
 	"htpatcher/internal/domain"
 	"htpatcher/internal/domain/rpgmaker"
+	"htpatcher/internal/util"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -29,56 +32,64 @@ func NewEngine(logger Logger) *Engine {
 	}
 }
 
-// PatchDataFile patches a single data file based on its type
-func (e *Engine) PatchDataFile(ctx context.Context, filePath string, patchInfo *domain.PatchInfo) error {
+// PatchDataFile patches a single data file of a game made with the given engine based on its type. The command hook may be nil.
+func (e *Engine) PatchDataFile(ctx context.Context, filePath string, engine string, patchInfo *domain.PatchInfo, hook *CommandHook) error {
 	filename := filepath.Base(filePath)
 	e.logger.Info("Patching: " + filename)
 
 	fileType := getDataFileTypeMap(filePath)
+	fields := dataFieldsOf(filePath, patchInfo.Config)
+	if !slices.Contains(dataPatcherTypes, fileType) && len(fields.fields) == 0 {
+		return nil
+	}
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return err
 	}
 
-	var patchedData []byte
+	patchedData := data
 	var patchError error
 
 	switch fileType {
-	case "actors":
-		patchedData, patchError = patchActors(data, patchInfo)
-	case "armors":
-		patchedData, patchError = patchArmors(data, patchInfo)
-	case "classes":
-		patchedData, patchError = patchClasses(data, patchInfo)
 	case "commonevents":
-		patchedData, patchError = patchCommonEvents(data, patchInfo)
-	case "enemies":
-		patchedData, patchError = patchEnemies(data, patchInfo)
-	case "items":
-		patchedData, patchError = patchItems(data, patchInfo)
+		patchedData, patchError = patchCommonEvents(data, patchInfo, hook)
 	case "map":
-		patchedData, patchError = patchMap(data, patchInfo)
-	case "skills":
-		patchedData, patchError = patchSkills(data, patchInfo)
-	case "states":
-		patchedData, patchError = patchStates(data, patchInfo)
+		patchedData, patchError = patchMap(data, patchInfo, hook)
 	case "system":
-		patchedData, patchError = patchSystem(data, patchInfo)
+		patchedData, patchError = patchSystem(data, patchInfo, engine)
 	case "troops":
-		patchedData, patchError = patchTroops(data, patchInfo)
-	case "weapons":
-		patchedData, patchError = patchWeapons(data, patchInfo)
-	default:
-		return nil
+		patchedData, patchError = patchTroops(data, patchInfo, hook)
 	}
-
 	if patchError != nil {
 		return patchError
 	}
 
+	// The other files only have fields, which the patch config may add to any file
+	if len(fields.fields) > 0 {
+		patchedData, patchError = patchDataFields(patchedData, fields.fields, patchInfo)
+		if patchError != nil {
+			return patchError
+		}
+	}
+
+	// Only rewrite the values that changed so the rest of the file stays byte for byte identical
+	spliced, err := util.SpliceJSON(data, patchedData)
+	if err != nil {
+		e.logger.Warn(fmt.Sprintf("Could not keep the formatting of %s: %s", filename, err.Error()))
+	} else {
+		patchedData = spliced
+	}
+
 	return os.WriteFile(filePath, patchedData, 0644)
 }
 
+// dataPatcherTypes are the file types with their own patcher instead of a list of fields
+var dataPatcherTypes = []string{"commonevents", "map", "system", "troops"}
+
+// OptionalDataFiles are the data files only translated when the patch lists them in dataFilesToPatch,
+// as their names mostly show in the editor
+var OptionalDataFiles = []string{"mapinfos", "animations", "tilesets"}
+
 // getDataFileTypeMap determines the file type from the filename
 func getDataFileTypeMap(filePath string) string {
 	fileTypeMap := map[string]string{
@@ -112,6 +123,6 @@ func getDataFileTypeMap(filePath string) string {
 }
 
 // PatchCommands patches event commands (used by maps, common events, troops)
-func (e *Engine) PatchCommands(commands []*rpgmaker.EventCommand, patchInfo *domain.PatchInfo) ([]*rpgmaker.EventCommand, error) {
-	return patchCommands(commands, patchInfo)
+func (e *Engine) PatchCommands(commands []*rpgmaker.EventCommand, patchInfo *domain.PatchInfo, hook *CommandHook) ([]*rpgmaker.EventCommand, error) {
+	return patchCommands(commands, patchInfo, hook)
 }
@@ -0,0 +1,286 @@
+package patcher
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// jsLiteral is a string or template literal of a JavaScript source
+type jsLiteral struct {
+	Start int    // Offset of the opening quote
+	End   int    // Offset after the closing quote
+	Quote byte   // ', " or `
+	Value string // Value with the escapes decoded
+	Line  int
+}
+
+// jsTokenizer finds the string literals of a JavaScript source, skipping comments and regular expressions.
+// Template literals with substitutions are not returned, but the literals inside their substitutions are.
+type jsTokenizer struct {
+	src      []byte
+	pos      int
+	literals []jsLiteral
+	line     int // Line at lineEnd
+	lineEnd  int // Offset up to which lines were counted
+}
+
+// scanJSLiterals returns the string and template literals of a JavaScript source in order
+func scanJSLiterals(src []byte) ([]jsLiteral, error) {
+	t := &jsTokenizer{src: src, line: 1}
+	if err := t.scanCode(false); err != nil {
+		return nil, err
+	}
+	return t.literals, nil
+}
+
+// scanCode scans code up to the end of the source, or up to the brace closing a template substitution
+func (t *jsTokenizer) scanCode(inSubstitution bool) error {
+	depth := 0
+	for t.pos < len(t.src) {
+		c := t.src[t.pos]
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			if inSubstitution && depth == 0 {
+				return nil
+			}
+			depth--
+		case c == '"' || c == '\'':
+			if err := t.scanString(); err != nil {
+				return err
+			}
+			continue
+		case c == '`':
+			if err := t.scanTemplate(); err != nil {
+				return err
+			}
+			continue
+		case c == '/' && t.peek(1) == '/':
+			for t.pos < len(t.src) && t.src[t.pos] != '\n' {
+				t.pos++
+			}
+			continue
+		case c == '/' && t.peek(1) == '*':
+			end := bytes.Index(t.src[t.pos+2:], []byte("*/"))
+			if end < 0 {
+				return t.errorf("unterminated comment")
+			}
+			t.pos += end + 4
+			continue
+		case c == '/' && isJSRegexStart(t.src, t.pos):
+			end := skipJSRegex(t.src, t.pos)
+			if end < 0 {
+				return t.errorf("unterminated regular expression")
+			}
+			t.pos = end + 1
+			continue
+		}
+		t.pos++
+	}
+	if inSubstitution {
+		return t.errorf("unterminated template substitution")
+	}
+	return nil
+}
+
+// scanString scans a quoted string literal
+func (t *jsTokenizer) scanString() error {
+	start := t.pos
+	end := skipJSString(t.src, start)
+	if end < 0 {
+		return t.errorf("unterminated string")
+	}
+	t.pos = end + 1
+	t.addLiteral(start, t.pos, t.src[start], string(t.src[start+1:end]))
+	return nil
+}
+
+// scanTemplate scans a template literal and the code of its substitutions
+func (t *jsTokenizer) scanTemplate() error {
+	start := t.pos
+	hasSubstitution := false
+	for t.pos++; t.pos < len(t.src); t.pos++ {
+		switch {
+		case t.src[t.pos] == '\\':
+			t.pos++
+		case t.src[t.pos] == '`':
+			t.pos++
+			if !hasSubstitution {
+				t.addLiteral(start, t.pos, '`', string(t.src[start+1:t.pos-1]))
+			}
+			return nil
+		case t.src[t.pos] == '$' && t.peek(1) == '{':
+			hasSubstitution = true
+			t.pos += 2
+			if err := t.scanCode(true); err != nil {
+				return err
+			}
+		}
+	}
+	t.pos = start
+	return t.errorf("unterminated template literal")
+}
+
+// addLiteral records a literal whose escapes can be decoded
+func (t *jsTokenizer) addLiteral(start int, end int, quote byte, raw string) {
+	value, ok := decodeJSString(raw)
+	if !ok {
+		return
+	}
+	t.literals = append(t.literals, jsLiteral{
+		Start: start,
+		End:   end,
+		Quote: quote,
+		Value: value,
+		Line:  t.lineAt(start),
+	})
+}
+
+// lineAt returns the line of an offset at or after the previous one
+func (t *jsTokenizer) lineAt(offset int) int {
+	t.line += bytes.Count(t.src[t.lineEnd:offset], []byte("\n"))
+	t.lineEnd = offset
+	return t.line
+}
+
+func (t *jsTokenizer) peek(offset int) byte {
+	if t.pos+offset < len(t.src) {
+		return t.src[t.pos+offset]
+	}
+	return 0
+}
+
+func (t *jsTokenizer) errorf(format string, args ...any) error {
+	line := bytes.Count(t.src[:min(t.pos, len(t.src))], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// decodeJSString decodes the escapes of the raw content of a literal
+func decodeJSString(raw string) (string, bool) {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	if !strings.Contains(raw, "\\") {
+		return raw, true
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			b.WriteByte(raw[i])
+			continue
+		}
+		i++
+		if i >= len(raw) {
+			return "", false
+		}
+		switch c := raw[i]; c {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		case '0':
+			if i+1 < len(raw) && raw[i+1] >= '0' && raw[i+1] <= '9' {
+				return "", false // Legacy octal escape
+			}
+			b.WriteByte(0)
+		case '\n':
+			// Line continuation
+		case 'x':
+			if i+2 >= len(raw) {
+				return "", false
+			}
+			code, err := strconv.ParseUint(raw[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", false
+			}
+			b.WriteRune(rune(code))
+			i += 2
+		case 'u':
+			code, length, ok := decodeJSUnicodeEscape(raw[i+1:])
+			if !ok {
+				return "", false
+			}
+			i += length
+			// Join surrogate pairs written as two escapes
+			if code >= 0xD800 && code < 0xDC00 && strings.HasPrefix(raw[i+1:], "\\u") {
+				if low, lowLength, ok := decodeJSUnicodeEscape(raw[i+3:]); ok && low >= 0xDC00 && low < 0xE000 {
+					code = 0x10000 + (code-0xD800)<<10 + (low - 0xDC00)
+					i += 2 + lowLength
+				}
+			}
+			if !utf8.ValidRune(code) {
+				return "", false
+			}
+			b.WriteRune(code)
+		default:
+			if c >= '1' && c <= '9' {
+				return "", false // Legacy octal escape
+			}
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), true
+}
+
+// decodeJSUnicodeEscape decodes the XXXX or {X...} part of a \u escape and returns its length
+func decodeJSUnicodeEscape(s string) (rune, int, bool) {
+	if strings.HasPrefix(s, "{") {
+		end := strings.IndexByte(s, '}')
+		if end < 2 {
+			return 0, 0, false
+		}
+		code, err := strconv.ParseUint(s[1:end], 16, 32)
+		if err != nil || code > utf8.MaxRune {
+			return 0, 0, false
+		}
+		return rune(code), end + 1, true
+	}
+	if len(s) < 4 {
+		return 0, 0, false
+	}
+	code, err := strconv.ParseUint(s[:4], 16, 16)
+	if err != nil {
+		return 0, 0, false
+	}
+	return rune(code), 4, true
+}
+
+// encodeJSString writes a value as a literal with the given quote
+func encodeJSString(value string, quote byte) string {
+	var b strings.Builder
+	b.WriteByte(quote)
+	for i, r := range value {
+		switch {
+		case r == '\\':
+			b.WriteString(`\\`)
+		case r == rune(quote):
+			b.WriteByte('\\')
+			b.WriteByte(quote)
+		case quote == '`' && r == '$' && strings.HasPrefix(value[i+1:], "{"):
+			b.WriteString(`\$`)
+		case quote != '`' && r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\u2028' || r == '\u2029':
+			fmt.Fprintf(&b, `\u%04x`, r)
+		case r < 0x20 && r != '\n' && r != '\t':
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte(quote)
+	return b.String()
+}
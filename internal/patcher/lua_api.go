@@ -0,0 +1,364 @@
+package patcher
+
+import (
+	"fmt"
+	"htpatcher/internal/util"
+	"sort"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Metatable fields describing tables decoded from JSON
+const (
+	luaJSONType   = "__jsontype"   // "object" or "array"
+	luaJSONKeys   = "__jsonkeys"   // Keys of an object in order
+	luaJSONString = "__jsonstring" // The value was a JSON string inside JSON and is encoded back to one
+)
+
+const (
+	luaJSONObject   = "object"
+	luaJSONArray    = "array"
+	luaMaxJSONDepth = 512
+)
+
+// luaJSONNull is the value of the jsonNull userdata standing for JSON null,
+// as nil cannot be stored in a table without removing the array item or object key
+type luaJSONNull struct{}
+
+// luaAPI provides the functions patch scripts can call during a patch run.
+// A table named shared keeps its content between the scripts of the run.
+type luaAPI struct {
+	sandbox    *luaSandbox
+	logger     Logger
+	dictionary map[string]string
+	newIndex   *lua.LFunction
+	null       *lua.LUserData
+}
+
+// newLuaAPI registers the patch script functions in a sandbox
+func newLuaAPI(sandbox *luaSandbox, logger Logger, dictionary map[string]string) *luaAPI {
+	api := &luaAPI{sandbox: sandbox, logger: logger, dictionary: dictionary}
+	L := sandbox.L
+	api.newIndex = L.NewFunction(api.objectNewIndex)
+	api.null = L.NewUserData()
+	api.null.Value = luaJSONNull{}
+	api.null.Metatable = L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"__tostring": func(L *lua.LState) int {
+			L.Push(lua.LString("null"))
+			return 1
+		},
+	})
+
+	L.SetGlobal("getTranslationByKey", L.NewFunction(api.getTranslationByKey))
+	L.SetGlobal("hasTranslation", L.NewFunction(api.hasTranslation))
+	L.SetGlobal("wrap", L.NewFunction(luaWrap))
+	L.SetGlobal("jsonDecode", L.NewFunction(api.jsonDecode))
+	L.SetGlobal("jsonDecodeDeep", L.NewFunction(api.jsonDecodeDeep))
+	L.SetGlobal("jsonEncode", L.NewFunction(api.jsonEncode))
+	L.SetGlobal("jsonObject", L.NewFunction(api.jsonObject))
+	L.SetGlobal("jsonArray", L.NewFunction(api.jsonArray))
+	L.SetGlobal("jsonKeys", L.NewFunction(api.jsonKeys))
+	L.SetGlobal("jsonNull", api.null)
+	L.SetGlobal("log", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"info":  api.logFunc(logger.Info),
+		"warn":  api.logFunc(logger.Warn),
+		"error": api.logFunc(logger.Error),
+	}))
+	L.SetGlobal("shared", L.NewTable())
+	return api
+}
+
+// getTranslationByKey returns the translation of a text, or the text itself when it has none
+func (api *luaAPI) getTranslationByKey(L *lua.LState) int {
+	original := L.CheckString(1)
+	translation, ok := api.dictionary[util.GetTranslationKey(original)]
+	if !ok {
+		translation = original
+	}
+	L.Push(lua.LString(translation))
+	return 1
+}
+
+// hasTranslation tells whether the dictionary has an entry for a text
+func (api *luaAPI) hasTranslation(L *lua.LState) int {
+	_, ok := api.dictionary[util.GetTranslationKey(L.CheckString(1))]
+	L.Push(lua.LBool(ok))
+	return 1
+}
+
+// luaWrap wraps a text to a width, the default message width when omitted
+func luaWrap(L *lua.LState) int {
+	L.Push(lua.LString(util.Wrap(L.CheckString(1), L.OptInt(2, 0))))
+	return 1
+}
+
+// logFunc returns a function logging its arguments with the plugin of the running script
+func (api *luaAPI) logFunc(log func(string)) lua.LGFunction {
+	return func(L *lua.LState) int {
+		parts := make([]string, L.GetTop())
+		for i := range parts {
+			parts[i] = L.ToStringMeta(L.Get(i + 1)).String()
+		}
+		log(fmt.Sprintf("[%s] %s", api.sandbox.scriptName, strings.Join(parts, " ")))
+		return 0
+	}
+}
+
+// jsonDecode decodes JSON into tables that keep the key order of objects
+func (api *luaAPI) jsonDecode(L *lua.LState) int {
+	return api.decode(L, false)
+}
+
+// jsonDecodeDeep is jsonDecode that also decodes strings holding JSON objects or arrays,
+// as MZ stores structs and lists in plugin parameters. They are encoded back to strings.
+func (api *luaAPI) jsonDecodeDeep(L *lua.LState) int {
+	return api.decode(L, true)
+}
+
+func (api *luaAPI) decode(L *lua.LState, deep bool) int {
+	value, err := util.ParseJSON([]byte(L.CheckString(1)))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(api.toLuaValue(L, value, deep))
+	return 1
+}
+
+// jsonEncode encodes a value, writing decoded objects with their original key order
+func (api *luaAPI) jsonEncode(L *lua.LState) int {
+	value, err := fromLuaValue(L.Get(1), 0)
+	if err == nil {
+		var data []byte
+		if data, err = util.MarshalUnescaped(value); err == nil {
+			L.Push(lua.LString(data))
+			return 1
+		}
+	}
+	L.Push(lua.LNil)
+	L.Push(lua.LString(err.Error()))
+	return 2
+}
+
+// jsonObject creates an empty table encoded as an object, keeping keys in the order they are set
+func (api *luaAPI) jsonObject(L *lua.LState) int {
+	L.Push(api.newJSONTable(L, luaJSONObject))
+	return 1
+}
+
+// jsonArray creates an empty table encoded as an array
+func (api *luaAPI) jsonArray(L *lua.LState) int {
+	L.Push(api.newJSONTable(L, luaJSONArray))
+	return 1
+}
+
+// jsonKeys returns the keys of a table in the order they are encoded
+func (api *luaAPI) jsonKeys(L *lua.LState) int {
+	keys := L.NewTable()
+	for _, key := range orderedKeys(L.CheckTable(1)) {
+		keys.Append(key)
+	}
+	L.Push(keys)
+	return 1
+}
+
+// objectNewIndex records the keys added to a decoded object
+func (api *luaAPI) objectNewIndex(L *lua.LState) int {
+	table := L.CheckTable(1)
+	key := L.CheckAny(2)
+	value := L.CheckAny(3)
+	table.RawSet(key, value)
+	if meta, ok := table.Metatable.(*lua.LTable); ok && value != lua.LNil {
+		if keys, ok := meta.RawGetString(luaJSONKeys).(*lua.LTable); ok {
+			keys.Append(key)
+		}
+	}
+	return 0
+}
+
+// newJSONTable creates a table marked as a JSON object or array
+func (api *luaAPI) newJSONTable(L *lua.LState, jsonType string) *lua.LTable {
+	meta := L.NewTable()
+	meta.RawSetString(luaJSONType, lua.LString(jsonType))
+	if jsonType == luaJSONObject {
+		meta.RawSetString(luaJSONKeys, L.NewTable())
+		meta.RawSetString("__newindex", api.newIndex)
+	}
+	table := L.NewTable()
+	table.Metatable = meta
+	return table
+}
+
+// toLuaValue converts a decoded JSON value to Lua, null to jsonNull
+func (api *luaAPI) toLuaValue(L *lua.LState, value any, deep bool) lua.LValue {
+	switch v := value.(type) {
+	case nil:
+		return api.null
+	case bool:
+		return lua.LBool(v)
+	case float64:
+		return lua.LNumber(v)
+	case int:
+		return lua.LNumber(v)
+	case string:
+		if deep {
+			if nested, ok := parseNestedJSON(v); ok {
+				table := api.toLuaValue(L, nested, deep).(*lua.LTable)
+				table.Metatable.(*lua.LTable).RawSetString(luaJSONString, lua.LTrue)
+				return table
+			}
+		}
+		return lua.LString(v)
+	case []any:
+		table := api.newJSONTable(L, luaJSONArray)
+		for i, item := range v {
+			table.RawSetInt(i+1, api.toLuaValue(L, item, deep))
+		}
+		return table
+	case *util.OrderedMap:
+		table := api.newJSONTable(L, luaJSONObject)
+		keys := table.Metatable.(*lua.LTable).RawGetString(luaJSONKeys).(*lua.LTable)
+		for _, key := range v.Keys {
+			keys.Append(lua.LString(key))
+			table.RawSetString(key, api.toLuaValue(L, v.Values[key], deep))
+		}
+		return table
+	default:
+		return lua.LNil
+	}
+}
+
+// parseNestedJSON decodes a string holding a JSON object or array
+func parseNestedJSON(s string) (any, bool) {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return nil, false
+	}
+	value, err := util.ParseJSON([]byte(trimmed))
+	return value, err == nil
+}
+
+// fromLuaValue converts a Lua value to a value encoded as JSON, nil and jsonNull to null
+func fromLuaValue(lv lua.LValue, depth int) (any, error) {
+	switch v := lv.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case *lua.LUserData:
+		if _, ok := v.Value.(luaJSONNull); ok {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot encode a %s as JSON", lv.Type())
+	case lua.LBool:
+		return bool(v), nil
+	case lua.LNumber:
+		return float64(v), nil
+	case lua.LString:
+		return string(v), nil
+	case *lua.LTable:
+		if depth >= luaMaxJSONDepth {
+			return nil, fmt.Errorf("tables are nested more than %d levels deep", luaMaxJSONDepth)
+		}
+		value, err := fromLuaTable(v, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if meta, ok := v.Metatable.(*lua.LTable); ok && lua.LVAsBool(meta.RawGetString(luaJSONString)) {
+			data, err := util.MarshalUnescaped(value)
+			if err != nil {
+				return nil, err
+			}
+			return string(data), nil
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("cannot encode a %s as JSON", lv.Type())
+	}
+}
+
+// fromLuaTable converts a table to an array or an object.
+// Tables without a JSON type are arrays when their keys are 1..n. An array item set to nil is encoded as null.
+func fromLuaTable(table *lua.LTable, depth int) (any, error) {
+	jsonType := ""
+	if meta, ok := table.Metatable.(*lua.LTable); ok {
+		jsonType = meta.RawGetString(luaJSONType).String()
+	}
+	if jsonType != luaJSONObject && jsonType != luaJSONArray {
+		jsonType = luaJSONObject
+		if length := table.Len(); length > 0 && countKeys(table) == length {
+			jsonType = luaJSONArray
+		}
+	}
+
+	if jsonType == luaJSONArray {
+		arr := make([]any, arrayLength(table))
+		for i := range arr {
+			value, err := fromLuaValue(table.RawGetInt(i+1), depth)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = value
+		}
+		return arr, nil
+	}
+
+	object := util.NewOrderedMap()
+	for _, key := range orderedKeys(table) {
+		value, err := fromLuaValue(table.RawGet(key), depth)
+		if err != nil {
+			return nil, err
+		}
+		object.Set(key.String(), value)
+	}
+	return object, nil
+}
+
+// orderedKeys returns the keys of a table: first the recorded keys of a decoded object, then the others sorted
+func orderedKeys(table *lua.LTable) []lua.LValue {
+	keys := []lua.LValue{}
+	seen := map[lua.LValue]bool{}
+	if meta, ok := table.Metatable.(*lua.LTable); ok {
+		if recorded, ok := meta.RawGetString(luaJSONKeys).(*lua.LTable); ok {
+			for i := 1; i <= recorded.Len(); i++ {
+				key := recorded.RawGetInt(i)
+				if !seen[key] && table.RawGet(key) != lua.LNil {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+
+	var others []lua.LValue
+	table.ForEach(func(key, _ lua.LValue) {
+		if !seen[key] {
+			others = append(others, key)
+		}
+	})
+	sort.Slice(others, func(i, j int) bool {
+		return others[i].String() < others[j].String()
+	})
+	return append(keys, others...)
+}
+
+// arrayLength returns the highest positive integer key of a table, which unlike the
+// length operator does not depend on where the items set to nil are
+func arrayLength(table *lua.LTable) int {
+	length := 0
+	table.ForEach(func(key, _ lua.LValue) {
+		if n, ok := key.(lua.LNumber); ok && n >= 1 && n == lua.LNumber(int(n)) && int(n) > length {
+			length = int(n)
+		}
+	})
+	return length
+}
+
+// countKeys returns the number of keys of a table
+func countKeys(table *lua.LTable) int {
+	count := 0
+	table.ForEach(func(_, _ lua.LValue) {
+		count++
+	})
+	return count
+}
@@ -0,0 +1,84 @@
+package patcher
+
+import (
+	"context"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// testLogger discards log messages
+type testLogger struct{}
+
+func (testLogger) Info(string)    {}
+func (testLogger) Success(string) {}
+func (testLogger) Error(string)   {}
+func (testLogger) Warn(string)    {}
+
+// runLuaFunction loads a script in a new sandbox with the patch script API and calls one of its functions with a string
+func runLuaFunction(t *testing.T, script string, name string, argument string) string {
+	t.Helper()
+	sandbox := newLuaSandbox(context.Background())
+	defer sandbox.Close()
+	newLuaAPI(sandbox, testLogger{}, map[string]string{})
+
+	var result lua.LValue
+	err := sandbox.run(func() error {
+		env, err := sandbox.load(name, script)
+		if err != nil {
+			return err
+		}
+		result, err = sandbox.call(name, env.RawGetString(name), lua.LString(argument))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("%s(%q): %v", name, argument, err)
+	}
+	return result.String()
+}
+
+// TestJSONRoundTrip checks that decoding and encoding JSON in a script keeps nulls, key order and nested JSON strings
+func TestJSONRoundTrip(t *testing.T) {
+	script := `
+function roundTrip(s)
+  return jsonEncode(jsonDecode(s))
+end
+function roundTripDeep(s)
+  return jsonEncode(jsonDecodeDeep(s))
+end`
+	for _, data := range []string{
+		`{"a":[1,null,2],"b":null,"c":"x"}`,
+		`[null]`,
+		`[1,null]`,
+		`[null,{"z":null,"y":[]}]`,
+		`{"list":"[1,null,{\"a\":null}]"}`,
+		`null`,
+	} {
+		if actual := runLuaFunction(t, script, "roundTrip", data); actual != data {
+			t.Errorf("roundTrip(%s) = %s", data, actual)
+		}
+		if actual := runLuaFunction(t, script, "roundTripDeep", data); actual != data {
+			t.Errorf("roundTripDeep(%s) = %s", data, actual)
+		}
+	}
+}
+
+// TestJSONNull checks that scripts can compare values to jsonNull and set them to it,
+// and that an array item set to nil is null unless it was the last one
+func TestJSONNull(t *testing.T) {
+	script := `
+function edit(s)
+  local value = jsonDecode(s)
+  assert(value.a[2] == jsonNull and value.b == jsonNull and #value.a == 4)
+  assert(tostring(jsonNull) == "null")
+  value.a[1] = jsonNull
+  value.a[3] = nil
+  value.e[2] = nil
+  value.d = jsonNull
+  return jsonEncode(value)
+end`
+	expected := `{"a":[null,null,null,3],"b":null,"c":"x","e":[1],"d":null}`
+	if actual := runLuaFunction(t, script, "edit", `{"a":[1,null,2,3],"b":null,"c":"x","e":[1,2]}`); actual != expected {
+		t.Errorf("edit = %s, expected %s", actual, expected)
+	}
+}
@@ -0,0 +1,236 @@
+package patcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime/metrics"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Limits of the sandbox patch scripts run in
+const (
+	luaTimeout          = 10 * time.Second
+	luaMemoryLimit      = 256 << 20 // Heap growth allowed while a script runs
+	luaMemoryInterval   = 20 * time.Millisecond
+	luaCallStackSize    = 200
+	luaRegistryMaxSize  = 1 << 20 // Slots of the value stack
+	luaMaxStringLength  = 16 << 20
+	luaHeapObjectsBytes = "/memory/classes/heap/objects:bytes"
+)
+
+var (
+	errLuaTimeout     = fmt.Errorf("script did not finish within %s", luaTimeout)
+	errLuaMemoryLimit = fmt.Errorf("script used more than %d MB of memory", luaMemoryLimit>>20)
+)
+
+// luaUnsafeGlobals are the functions of the base library that reach the file system or other environments
+var luaUnsafeGlobals = []string{"dofile", "loadfile", "load", "loadstring", "require", "module", "getfenv", "setfenv", "collectgarbage", "newproxy"}
+
+// luaErrorRegex splits the chunk name and line from Lua runtime and syntax error messages
+var luaErrorRegex = regexp.MustCompile(`(?s)^[^\n]*?(?::(\d+):| line:(\d+)\(column:\d+\))\s*(.*)$`)
+
+// luaSandbox is a Lua state with only the safe standard libraries.
+// It can run several scripts, each bounded in time and memory and with its own globals.
+type luaSandbox struct {
+	L          *lua.LState
+	ctx        context.Context
+	runCtx     context.Context // Context of the script that is running
+	scriptName string          // Plugin or hook of the script that is running, for log messages
+}
+
+// newLuaSandbox creates a sandbox whose scripts stop when ctx is done
+func newLuaSandbox(ctx context.Context) *luaSandbox {
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:        true,
+		CallStackSize:       luaCallStackSize,
+		RegistryMaxSize:     luaRegistryMaxSize,
+		MinimizeStackMemory: true,
+	})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range luaUnsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+	// string.rep runs in Go, where the memory watcher cannot stop it in time
+	if stringLib, ok := L.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		stringLib.RawSetString("rep", L.NewFunction(luaStringRep))
+	}
+
+	return &luaSandbox{L: L, ctx: ctx, runCtx: ctx}
+}
+
+// run calls fn with the Lua state stopping when the timeout expires or the memory limit is exceeded
+func (s *luaSandbox) run(fn func() error) error {
+	ctx, cancel := context.WithCancelCause(s.ctx)
+	done := make(chan struct{})
+	s.runCtx = ctx
+	s.L.SetContext(ctx)
+	go watch(ctx, cancel, done)
+	defer func() {
+		close(done)
+		s.L.RemoveContext()
+		cancel(nil)
+	}()
+	return fn()
+}
+
+// watch cancels a script when it runs for too long or the heap grows past the memory limit
+func watch(ctx context.Context, cancel context.CancelCauseFunc, done chan struct{}) {
+	timeout := time.NewTimer(luaTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(luaMemoryInterval)
+	defer ticker.Stop()
+
+	baseline := heapObjectsBytes()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-timeout.C:
+			cancel(errLuaTimeout)
+			return
+		case <-ticker.C:
+			if heapObjectsBytes() > baseline+luaMemoryLimit {
+				cancel(errLuaMemoryLimit)
+				return
+			}
+		}
+	}
+}
+
+// Close releases the Lua state
+func (s *luaSandbox) Close() {
+	s.L.Close()
+}
+
+// load runs a script with its own globals, which fall back to the globals of the sandbox, and returns them
+func (s *luaSandbox) load(name string, script string) (*lua.LTable, error) {
+	fn, err := s.L.Load(strings.NewReader(script), name)
+	if err != nil {
+		return nil, s.scriptError(name, err)
+	}
+	env := s.L.NewTable()
+	envMeta := s.L.NewTable()
+	envMeta.RawSetString("__index", s.L.Get(lua.GlobalsIndex))
+	s.L.SetMetatable(env, envMeta)
+	s.L.SetFEnv(fn, env)
+
+	s.L.Push(fn)
+	if err := s.L.PCall(0, 0, nil); err != nil {
+		return nil, s.scriptError(name, err)
+	}
+	return env, nil
+}
+
+// call calls a function of a script and returns its first result
+func (s *luaSandbox) call(name string, fn lua.LValue, args ...lua.LValue) (lua.LValue, error) {
+	s.L.Push(fn)
+	for _, arg := range args {
+		s.L.Push(arg)
+	}
+	if err := s.L.PCall(len(args), 1, nil); err != nil {
+		return nil, s.scriptError(name, err)
+	}
+	result := s.L.Get(-1)
+	s.L.Pop(1)
+	return result, nil
+}
+
+// RunPatch runs a parameters patch script and calls its patch function with the plugin parameters.
+// Errors name the plugin and, when known, the script line that failed.
+func (s *luaSandbox) RunPatch(pluginName string, script string, parameters string) (string, error) {
+	s.scriptName = pluginName
+	name := "plugin " + pluginName
+	var patched string
+	err := s.run(func() error {
+		env, err := s.load(name, script)
+		if err != nil {
+			return err
+		}
+		patch := env.RawGetString("patch")
+		if patch.Type() != lua.LTFunction {
+			return fmt.Errorf("%s: the script does not define a patch function", name)
+		}
+		result, err := s.call(name, patch, lua.LString(parameters))
+		if err != nil {
+			return err
+		}
+		str, ok := result.(lua.LString)
+		if !ok {
+			return fmt.Errorf("%s: patch returned a %s instead of a JSON string", name, result.Type())
+		}
+		if !json.Valid([]byte(str)) {
+			return fmt.Errorf("%s: patch returned invalid JSON", name)
+		}
+		patched = string(str)
+		return nil
+	})
+	return patched, err
+}
+
+// scriptError describes a failed script with its name and line
+func (s *luaSandbox) scriptError(name string, err error) error {
+	message := err.Error()
+	var apiErr *lua.ApiError
+	if errors.As(err, &apiErr) && apiErr.Object != nil {
+		message = apiErr.Object.String()
+	}
+	if cause := context.Cause(s.runCtx); cause != nil && !errors.Is(cause, context.Canceled) {
+		message = strings.Replace(message, s.runCtx.Err().Error(), cause.Error(), 1)
+	}
+
+	match := luaErrorRegex.FindStringSubmatch(strings.TrimSpace(message))
+	if match == nil {
+		return fmt.Errorf("%s: %s", name, message)
+	}
+	line := match[1]
+	if line == "" {
+		line = match[2]
+	}
+	return fmt.Errorf("%s, line %s: %s", name, line, strings.TrimSpace(match[3]))
+}
+
+// luaStringRep is string.rep with a cap on the length of the result
+func luaStringRep(L *lua.LState) int {
+	str := L.CheckString(1)
+	n := L.CheckInt(2)
+	if n <= 0 || str == "" {
+		L.Push(lua.LString(""))
+		return 1
+	}
+	if len(str) > luaMaxStringLength/n {
+		L.RaiseError("string.rep result is longer than %d MB", luaMaxStringLength>>20)
+		return 0
+	}
+	L.Push(lua.LString(strings.Repeat(str, n)))
+	return 1
+}
+
+// heapObjectsBytes returns the memory held by heap objects, live or not yet collected
+func heapObjectsBytes() uint64 {
+	sample := []metrics.Sample{{Name: luaHeapObjectsBytes}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
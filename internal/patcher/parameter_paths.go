@@ -0,0 +1,195 @@
+package patcher
+
+import (
+	"fmt"
+	"htpatcher/internal/domain"
+	"htpatcher/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Types of the texts found at a parameter path
+const (
+	ParameterTypeText = "text" // Translated as is, the default
+	ParameterTypeWrap = "wrap" // Translated and wrapped to the wrap width of the patch
+)
+
+// pathStep is a step of a parameter path: an object key, an array index or a wildcard
+type pathStep struct {
+	Key      string
+	Index    int
+	IsIndex  bool
+	Wildcard bool
+}
+
+// parseParameterPath parses a JSONPath-like path such as $.messages[*].text, $['Key Name'][0] or $.list.*
+// $ alone is the root value. Strings holding JSON objects or arrays are descended into as if they were decoded.
+func parseParameterPath(path string) ([]pathStep, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if rest != "" && rest[0] != '.' && rest[0] != '[' {
+		rest = "." + rest
+	}
+	steps := []pathStep{}
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, "[*]"):
+			steps = append(steps, pathStep{Wildcard: true})
+			rest = rest[3:]
+		case strings.HasPrefix(rest, ".*"):
+			steps = append(steps, pathStep{Wildcard: true})
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "['"):
+			end := strings.Index(rest, "']")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %s: unterminated key", path)
+			}
+			steps = append(steps, pathStep{Key: rest[2:end]})
+			rest = rest[end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %s: unterminated index", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("invalid path %s: invalid index %s", path, rest[1:end])
+			}
+			steps = append(steps, pathStep{Index: index, IsIndex: true})
+			rest = rest[end+1:]
+		case strings.HasPrefix(rest, "."):
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid path %s: empty key", path)
+			}
+			steps = append(steps, pathStep{Key: rest[1 : end+1]})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("invalid path %s: unexpected %q", path, rest[0])
+		}
+	}
+	return steps, nil
+}
+
+// CheckParameterPath checks the syntax of a parameter path
+func CheckParameterPath(path string) error {
+	_, err := parseParameterPath(path)
+	return err
+}
+
+// CheckParameterPathToPatch checks the path and the type of a parameter path to patch
+func CheckParameterPathToPatch(path domain.ParameterPathToPatch) error {
+	if !slices.Contains([]string{"", ParameterTypeText, ParameterTypeWrap}, path.Type) {
+		return fmt.Errorf("invalid type %s of path %s", path.Type, path.Path)
+	}
+	return CheckParameterPath(path.Path)
+}
+
+// visitParameterPath calls fn on each string found at the end of a path and stores what it returns.
+// Strings holding JSON are decoded to follow the path and encoded back when something changed.
+func visitParameterPath(value any, steps []pathStep, fn func(text string) string) any {
+	return visitParameterPathAt(value, steps, nil, func(text string, _ []string) string {
+		return fn(text)
+	})
+}
+
+// visitParameterPathAt is visitParameterPath also passing fn the keys and indexes leading to each string
+func visitParameterPathAt(value any, steps []pathStep, trail []string, fn func(text string, trail []string) string) any {
+	if text, ok := value.(string); ok {
+		if len(steps) == 0 {
+			return fn(text, trail)
+		}
+		nested, ok := parseNestedJSON(text)
+		if !ok {
+			return text
+		}
+		original, err := util.MarshalUnescaped(nested)
+		if err != nil {
+			return text
+		}
+		encoded, err := util.MarshalUnescaped(visitParameterPathAt(nested, steps, trail, fn))
+		// Keep the original string when nothing changed so its formatting is kept
+		if err != nil || string(original) == string(encoded) {
+			return text
+		}
+		return string(encoded)
+	}
+	if len(steps) == 0 {
+		return value
+	}
+
+	step := steps[0]
+	trail = trail[:len(trail):len(trail)]
+	switch v := value.(type) {
+	case *util.OrderedMap:
+		if step.IsIndex {
+			return v
+		}
+		for _, key := range v.Keys {
+			if step.Wildcard || key == step.Key {
+				v.Values[key] = visitParameterPathAt(v.Values[key], steps[1:], append(trail, key), fn)
+			}
+		}
+	case []any:
+		for i := range v {
+			if step.Wildcard || (step.IsIndex && i == step.Index) {
+				v[i] = visitParameterPathAt(v[i], steps[1:], append(trail, strconv.Itoa(i)), fn)
+			}
+		}
+	}
+	return value
+}
+
+// patchParameterPaths translates the texts found at the given paths of a JSON document
+func patchParameterPaths(parameters []byte, paths []domain.ParameterPathToPatch, dictionary map[string]string, wrapWidth int) ([]byte, error) {
+	for _, path := range paths {
+		if err := CheckParameterPathToPatch(path); err != nil {
+			return nil, err
+		}
+	}
+	value, err := util.ParseJSON(parameters)
+	if err != nil {
+		return nil, err
+	}
+	return util.MarshalUnescaped(translateParameterPaths(value, paths, dictionary, wrapWidth))
+}
+
+// translateParameterPaths translates the texts found at the given paths of a decoded JSON value, wrapping the ones of type wrap
+func translateParameterPaths(value any, paths []domain.ParameterPathToPatch, dictionary map[string]string, wrapWidth int) any {
+	for _, path := range paths {
+		steps, err := parseParameterPath(path.Path)
+		if err != nil {
+			continue // Checked when the patch is loaded
+		}
+		value = visitParameterPath(value, steps, func(text string) string {
+			translation, ok := dictionary[util.GetTranslationKey(text)]
+			if !ok {
+				return text
+			}
+			if path.Type == ParameterTypeWrap {
+				return util.Wrap(translation, wrapWidth)
+			}
+			return translation
+		})
+	}
+	return value
+}
+
+// parameterPathTexts returns the texts found at the given paths of a decoded JSON value with the path they were found at
+func parameterPathTexts(value any, paths []domain.ParameterPathToPatch) [][2]string {
+	var texts [][2]string
+	for _, path := range paths {
+		steps, err := parseParameterPath(path.Path)
+		if err != nil {
+			continue
+		}
+		visitParameterPath(value, steps, func(text string) string {
+			texts = append(texts, [2]string{text, path.Path})
+			return text
+		})
+	}
+	return texts
+}
@@ -4,14 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"htpatcher/internal/domain"
 	"htpatcher/internal/util"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
 )
 
 // PluginPatcher handles plugin patching operations
@@ -24,207 +27,176 @@ func NewPluginPatcher(logger Logger) *PluginPatcher {
 	return &PluginPatcher{logger: logger}
 }
 
-// ApplyReplaceRule applies a replace rule to a plugin file
+// ApplyReplaceRule applies a replace rule to a plugin file, or to the file the rule targets
 func (p *PluginPatcher) ApplyReplaceRule(ctx context.Context, jsPath string, pluginName string, replaceRule domain.PluginReplaceRule, ruleIndex int) error {
 	p.logger.Info("Applying replace rule on plugin " + pluginName)
 
-	pluginPath := filepath.Join(jsPath, "plugins", pluginName+".js")
-	data, err := os.ReadFile(pluginPath)
+	targetPath, err := replaceRuleTarget(jsPath, pluginName, replaceRule)
+	if err != nil {
+		return fmt.Errorf("replace rule #%d of plugin %s: %w", ruleIndex, pluginName, err)
+	}
+	data, err := os.ReadFile(targetPath)
 	if err != nil {
 		return err
 	}
 
 	// Normalize line endings by removing \r for cross-platform compatibility
 	normalizedData := bytes.ReplaceAll(data, []byte("\r"), []byte(""))
-	normalizedMatch := bytes.ReplaceAll([]byte(replaceRule.Match), []byte("\r"), []byte(""))
-	normalizedReplace := bytes.ReplaceAll([]byte(replaceRule.Replace), []byte("\r"), []byte(""))
+	patchedData, count, err := applyReplaceRule(normalizedData, replaceRule)
+	if err != nil {
+		return fmt.Errorf("replace rule #%d of plugin %s: %w", ruleIndex, pluginName, err)
+	}
 
-	// Check if match string exists in the normalized file
-	if !bytes.Contains(normalizedData, normalizedMatch) {
-		p.logger.Warn(fmt.Sprintf("Replace rule #%d was not applied on plugin %s", ruleIndex, pluginName))
+	if mismatch := replaceRuleMismatch(replaceRule, count); mismatch != "" {
+		message := fmt.Sprintf("Replace rule #%d %s on plugin %s", ruleIndex, mismatch, pluginName)
+		if replaceRule.Strict {
+			p.logger.Error(message)
+			return errors.New(message)
+		}
+		p.logger.Warn(message)
 	}
 
-	patchedData := bytes.ReplaceAll(normalizedData, normalizedMatch, normalizedReplace)
-	return os.WriteFile(pluginPath, patchedData, 0644)
+	return os.WriteFile(targetPath, patchedData, 0644)
 }
 
-// UpdatePluginsJs updates the plugins.js file with translated plugin parameters
-func (p *PluginPatcher) UpdatePluginsJs(ctx context.Context, pluginsJsPath string, pluginsToPatch []domain.PluginToPatch, dictionary map[string]string) error {
-	data, err := os.ReadFile(pluginsJsPath)
-	if err != nil {
-		return err
+// PluginFileTargets returns the files modified by the replace rules and the string translation of a plugin
+func PluginFileTargets(jsPath string, pluginToPatch domain.PluginToPatch) []string {
+	var targets []string
+	if pluginToPatch.TranslateStrings {
+		targets = append(targets, PluginJsPath(jsPath, pluginToPatch.Plugin))
 	}
-
-	jsContent := string(data)
-	startIndex := strings.Index(jsContent, "[")
-	endIndex := strings.LastIndex(jsContent, "]")
-	if startIndex == -1 || endIndex == -1 {
-		return nil
+	for _, rule := range pluginToPatch.ReplaceRules {
+		target, err := replaceRuleTarget(jsPath, pluginToPatch.Plugin, rule)
+		if err == nil && !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
 	}
+	return targets
+}
+
+// TranslatePluginStrings replaces the string literals of a plugin source that have a translation
+func (p *PluginPatcher) TranslatePluginStrings(ctx context.Context, jsPath string, pluginName string, dictionary map[string]string) error {
+	p.logger.Info("Translating strings of plugin " + pluginName)
 
-	pluginsJson := jsContent[startIndex : endIndex+1]
-	var plugins []domain.PluginData
-	err = json.Unmarshal([]byte(pluginsJson), &plugins)
+	pluginPath := PluginJsPath(jsPath, pluginName)
+	data, err := os.ReadFile(pluginPath)
 	if err != nil {
 		return err
 	}
+	literals, err := scanJSLiterals(data)
+	if err != nil {
+		return fmt.Errorf("plugin %s, %w", pluginName, err)
+	}
 
-	p.logger.Info("Updating plugins data")
-
-	for i := range plugins {
-		for _, pluginToPatch := range pluginsToPatch {
-			if plugins[i].Name == pluginToPatch.Plugin && pluginToPatch.ParametersPatchScript != "" {
-				p.logger.Info("Patching plugin data of: " + plugins[i].Name)
-
-				L := lua.NewState()
-				defer L.Close()
-				L.SetGlobal("getTranslationByKey", L.NewFunction(makeGetTranslationByKey(dictionary)))
-				L.SetGlobal("jsonDecode", L.NewFunction(jsonDecode))
-				L.SetGlobal("jsonEncode", L.NewFunction(jsonEncode))
-				if err := L.DoString(pluginToPatch.ParametersPatchScript); err != nil {
-					return err
-				}
-
-				fn := L.GetGlobal("patch")
-				L.Push(fn)
-				L.Push(lua.LString(string(plugins[i].Parameters)))
-				if err := L.PCall(1, 1, nil); err != nil {
-					return err
-				}
-
-				patchedParams := L.ToString(-1)
-				L.Pop(1)
-				plugins[i].Parameters = json.RawMessage(patchedParams)
-			}
+	var patched bytes.Buffer
+	last := 0
+	count := 0
+	for _, literal := range literals {
+		translation, ok := dictionary[util.GetTranslationKey(literal.Value)]
+		if !ok || translation == literal.Value {
+			continue
 		}
+		patched.Write(data[last:literal.Start])
+		patched.WriteString(encodeJSString(translation, literal.Quote))
+		last = literal.End
+		count++
+	}
+	if count == 0 {
+		p.logger.Warn("No string was translated in plugin " + pluginName)
+		return nil
 	}
+	patched.Write(data[last:])
+	p.logger.Info(fmt.Sprintf("Translated %d strings in plugin %s", count, pluginName))
+
+	return os.WriteFile(pluginPath, patched.Bytes(), 0644)
+}
 
-	patchedPluginsJson, err := json.Marshal(plugins)
+// CheckPatchScript compiles the parameters patch script of a plugin without running it
+func CheckPatchScript(pluginName string, script string) error {
+	chunk, err := parse.Parse(strings.NewReader(script), pluginName)
 	if err != nil {
 		return err
 	}
-
-	before := jsContent[:startIndex]
-	after := jsContent[endIndex+1:]
-	patchedData := before + string(patchedPluginsJson) + after
-
-	return os.WriteFile(pluginsJsPath, []byte(patchedData), 0644)
+	_, err = lua.Compile(chunk, pluginName)
+	return err
 }
 
-// Lua helper functions
-func makeGetTranslationByKey(dictionary map[string]string) func(*lua.LState) int {
-	return func(L *lua.LState) int {
-		original := L.ToString(1)
-		key := util.GetTranslationKey(original)
-		translation, ok := dictionary[key]
-		if !ok {
-			translation = original
-		}
-		L.Push(lua.LString(translation))
-		return 1
-	}
+// PluginJsPath returns the path of the source file of a plugin
+func PluginJsPath(jsPath string, pluginName string) string {
+	return filepath.Join(jsPath, "plugins", pluginName+".js")
 }
 
-func jsonDecode(L *lua.LState) int {
-	jsonStr := L.ToString(1)
-	var result interface{}
-	if err := json.Unmarshal([]byte(jsonStr), &result); err != nil {
-		L.Push(lua.LNil)
-		L.Push(lua.LString(err.Error()))
-		return 2
+// UpdatePluginsJs updates the plugins.js file with translated plugin parameters.
+// Only the parameters of patched plugins are rewritten, the rest of the file is kept as is.
+func (p *PluginPatcher) UpdatePluginsJs(ctx context.Context, pluginsJsPath string, pluginsToPatch []domain.PluginToPatch, dictionary map[string]string, wrapWidth int) error {
+	data, err := os.ReadFile(pluginsJsPath)
+	if err != nil {
+		return err
 	}
-	L.Push(convertToLuaValue(L, result))
-	return 1
-}
 
-func jsonEncode(L *lua.LState) int {
-	luaValue := L.Get(1)
-	jsonBytes, err := json.Marshal(convertFromLuaValue(luaValue))
+	startIndex, endIndex, ok := findPluginsArray(data)
+	if !ok {
+		return nil
+	}
+	plugins, err := parsePluginEntries(data, startIndex, endIndex)
 	if err != nil {
-		L.Push(lua.LNil)
-		L.Push(lua.LString(err.Error()))
-		return 2
+		return err
 	}
-	L.Push(lua.LString(string(jsonBytes)))
-	return 1
-}
 
-func convertToLuaValue(L *lua.LState, value interface{}) lua.LValue {
-	switch v := value.(type) {
-	case nil:
-		return lua.LNil
-	case bool:
-		return lua.LBool(v)
-	case float64:
-		return lua.LNumber(v)
-	case string:
-		return lua.LString(v)
-	case []interface{}:
-		table := L.NewTable()
-		for i, item := range v {
-			table.RawSetInt(i+1, convertToLuaValue(L, item))
-		}
-		return table
-	case map[string]interface{}:
-		table := L.NewTable()
-		for k, val := range v {
-			table.RawSetString(k, convertToLuaValue(L, val))
+	p.logger.Info("Updating plugins data")
+
+	// The scripts of a run share one sandbox so they can keep state in the shared table
+	var sandbox *luaSandbox
+	defer func() {
+		if sandbox != nil {
+			sandbox.Close()
 		}
-		return table
-	default:
-		return lua.LNil
-	}
-}
+	}()
 
-func convertFromLuaValue(lv lua.LValue) interface{} {
-	switch lv.Type() {
-	case lua.LTNil:
-		return nil
-	case lua.LTBool:
-		return bool(lv.(lua.LBool))
-	case lua.LTNumber:
-		return float64(lv.(lua.LNumber))
-	case lua.LTString:
-		return string(lv.(lua.LString))
-	case lua.LTTable:
-		table := lv.(*lua.LTable)
-		result := make(map[string]interface{})
-		isArray := true
-		maxIndex := 0
-
-		table.ForEach(func(key, value lua.LValue) {
-			if num, ok := key.(lua.LNumber); ok {
-				idx := int(num)
-				if idx > maxIndex {
-					maxIndex = idx
-				}
-				if idx < 1 || idx > maxIndex {
-					isArray = false
+	var patchedData bytes.Buffer
+	last := 0
+	for _, plugin := range plugins {
+		parameters := plugin.Parameters
+		for _, pluginToPatch := range pluginsToPatch {
+			if plugin.Name != pluginToPatch.Plugin || plugin.Parameters == nil {
+				continue
+			}
+			if len(pluginToPatch.ParameterPaths) > 0 {
+				p.logger.Info("Translating parameters of: " + plugin.Name)
+				patchedParams, err := patchParameterPaths(parameters, pluginToPatch.ParameterPaths, dictionary, wrapWidth)
+				if err != nil {
+					p.logger.Error("Failed to translate parameters: " + err.Error())
+					return err
 				}
-			} else {
-				isArray = false
+				parameters = patchedParams
 			}
-		})
-
-		if isArray && maxIndex > 0 {
-			arr := make([]interface{}, maxIndex)
-			table.ForEach(func(key, value lua.LValue) {
-				if num, ok := key.(lua.LNumber); ok {
-					idx := int(num) - 1
-					if idx >= 0 && idx < maxIndex {
-						arr[idx] = convertFromLuaValue(value)
-					}
+			if pluginToPatch.ParametersPatchScript != "" {
+				p.logger.Info("Patching plugin data of: " + plugin.Name)
+
+				if sandbox == nil {
+					sandbox = newLuaSandbox(ctx)
+					newLuaAPI(sandbox, p.logger, dictionary)
+				}
+				patchedParams, err := sandbox.RunPatch(pluginToPatch.Plugin, pluginToPatch.ParametersPatchScript, string(parameters))
+				if err != nil {
+					p.logger.Error("Parameters patch script failed: " + err.Error())
+					return err
 				}
-			})
-			return arr
+				// Keep one plugin per line
+				var compacted bytes.Buffer
+				if err := json.Compact(&compacted, []byte(patchedParams)); err != nil {
+					return err
+				}
+				parameters = compacted.Bytes()
+			}
+		}
+		if !bytes.Equal(parameters, plugin.Parameters) {
+			patchedData.Write(data[last:plugin.ParametersStart])
+			patchedData.Write(parameters)
+			last = plugin.ParametersEnd
 		}
-
-		table.ForEach(func(key, value lua.LValue) {
-			keyStr := key.String()
-			result[keyStr] = convertFromLuaValue(value)
-		})
-		return result
-	default:
-		return nil
 	}
+	patchedData.Write(data[last:])
+
+	return os.WriteFile(pluginsJsPath, patchedData.Bytes(), 0644)
 }
@@ -0,0 +1,121 @@
+package patcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// pluginEntry is a plugin object of the plugins.js array
+type pluginEntry struct {
+	Name            string
+	Parameters      json.RawMessage
+	ParametersStart int // Offset of the parameters value in the file
+	ParametersEnd   int
+}
+
+// findPluginsArray returns the offsets of the brackets of the plugins array, skipping comments and strings
+func findPluginsArray(src []byte) (int, int, bool) {
+	for i := 0; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '"' || c == '\'':
+			if i = skipJSString(src, i); i < 0 {
+				return 0, 0, false
+			}
+		case c == '`':
+			if i = skipJSTemplate(src, i); i < 0 {
+				return 0, 0, false
+			}
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end < 0 {
+				return 0, 0, false
+			}
+			i += end + 3
+		case c == '[':
+			end := matchJSDelimiter(src, i)
+			if end < 0 {
+				return 0, 0, false
+			}
+			return i, end, true
+		}
+	}
+	return 0, 0, false
+}
+
+// parsePluginEntries returns the plugins of the array between start and end with where their parameters are
+func parsePluginEntries(src []byte, start int, end int) ([]pluginEntry, error) {
+	var entries []pluginEntry
+	for i := start + 1; i < end; i++ {
+		switch c := src[i]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ',':
+		case c == '/' && src[i+1] == '/':
+			for i < end && src[i] != '\n' {
+				i++
+			}
+		case c == '/' && src[i+1] == '*':
+			commentEnd := bytes.Index(src[i+2:end], []byte("*/"))
+			if commentEnd < 0 {
+				return nil, errors.New("unterminated comment in plugins array")
+			}
+			i += commentEnd + 3
+		case c == '{':
+			objectEnd := matchJSDelimiter(src, i)
+			if objectEnd < 0 || objectEnd > end {
+				return nil, errors.New("unterminated plugin object")
+			}
+			entry, err := parsePluginEntry(src[i:objectEnd+1], i)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
+			i = objectEnd
+		default:
+			return nil, fmt.Errorf("unexpected %q in plugins array", c)
+		}
+	}
+	return entries, nil
+}
+
+// parsePluginEntry reads the name and the parameters of a plugin object found at offset
+func parsePluginEntry(object []byte, offset int) (pluginEntry, error) {
+	entry := pluginEntry{}
+	dec := json.NewDecoder(bytes.NewReader(object))
+	if _, err := dec.Token(); err != nil {
+		return entry, err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return entry, err
+		}
+		key, _ := token.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return entry, err
+		}
+		switch key {
+		case "name":
+			if err := json.Unmarshal(value, &entry.Name); err != nil {
+				return entry, err
+			}
+		case "parameters":
+			entry.Parameters = value
+			entry.ParametersEnd = offset + int(dec.InputOffset())
+			entry.ParametersStart = entry.ParametersEnd - len(value)
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return entry, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return entry, errors.New("unexpected data after plugin object")
+	}
+	return entry, nil
+}
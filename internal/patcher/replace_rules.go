@@ -0,0 +1,267 @@
+package patcher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"htpatcher/internal/domain"
+	"htpatcher/internal/util"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// CheckReplaceRule checks that the pattern and the target file of a replace rule are valid
+func CheckReplaceRule(rule domain.PluginReplaceRule) error {
+	if rule.Match == "" {
+		return errors.New("match is empty")
+	}
+	if rule.Regex {
+		if _, err := regexp.Compile(normalizeNewlines(rule.Match)); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	}
+	if rule.ExpectedMatches < 0 {
+		return errors.New("expected matches is negative")
+	}
+	if rule.File != "" {
+		if err := util.ValidateRelativePath(rule.File); err != nil {
+			return fmt.Errorf("invalid file: %w", err)
+		}
+	}
+	return nil
+}
+
+// replaceRuleTarget returns the path of the file a replace rule modifies
+func replaceRuleTarget(jsPath string, pluginName string, rule domain.PluginReplaceRule) (string, error) {
+	if rule.File == "" {
+		return PluginJsPath(jsPath, pluginName), nil
+	}
+	return util.SafeJoin(jsPath, rule.File)
+}
+
+// applyReplaceRule applies a replace rule to normalized data and returns the result with the number of matches
+func applyReplaceRule(data []byte, rule domain.PluginReplaceRule) ([]byte, int, error) {
+	if err := CheckReplaceRule(rule); err != nil {
+		return nil, 0, err
+	}
+
+	var replace func(scope []byte) ([]byte, int)
+	if rule.Regex {
+		pattern := regexp.MustCompile(normalizeNewlines(rule.Match))
+		template := normalizeNewlines(rule.Replace)
+		replace = func(scope []byte) ([]byte, int) {
+			count := len(pattern.FindAllIndex(scope, -1))
+			return pattern.ReplaceAll(scope, []byte(template)), count
+		}
+	} else {
+		match := []byte(normalizeNewlines(rule.Match))
+		replacement := []byte(normalizeNewlines(rule.Replace))
+		replace = func(scope []byte) ([]byte, int) {
+			return bytes.ReplaceAll(scope, match, replacement), bytes.Count(scope, match)
+		}
+	}
+
+	if rule.Function == "" {
+		patched, count := replace(data)
+		return patched, count, nil
+	}
+
+	// Only the bodies of the function are replaced
+	var patched bytes.Buffer
+	total := 0
+	last := 0
+	for _, body := range findFunctionBodies(data, rule.Function) {
+		patched.Write(data[last:body[0]])
+		scope, count := replace(data[body[0]:body[1]])
+		patched.Write(scope)
+		total += count
+		last = body[1]
+	}
+	patched.Write(data[last:])
+	return patched.Bytes(), total, nil
+}
+
+// replaceRuleMismatch describes how the number of matches of a rule differs from what it expects, if it does
+func replaceRuleMismatch(rule domain.PluginReplaceRule, count int) string {
+	if rule.ExpectedMatches == 0 && count == 0 {
+		return "was not applied"
+	}
+	if rule.ExpectedMatches > 0 && count != rule.ExpectedMatches {
+		return fmt.Sprintf("matched %d times instead of %d", count, rule.ExpectedMatches)
+	}
+	return ""
+}
+
+// normalizeNewlines removes \r for cross-platform compatibility
+func normalizeNewlines(s string) string {
+	return strings.ReplaceAll(s, "\r", "")
+}
+
+// findFunctionBodies returns the ranges of the bodies of the definitions of a function, without their braces.
+// Function declarations, function expressions and arrow functions assigned to the name, and class methods are found.
+func findFunctionBodies(src []byte, name string) [][2]int {
+	quoted := regexp.QuoteMeta(name)
+	header := regexp.MustCompile(`(?m)(?:^|[^\w$.])function\s+` + quoted + `\s*\(` +
+		`|(?:^|[^\w$])` + quoted + `\s*[=:]\s*(?:async\s+)?function\b[\w$\s]*\(` +
+		`|(?:^|[^\w$])` + quoted + `\s*[=:]\s*(?:async\s*)?\(` +
+		`|^[ \t]*(?:(?:static|async|get|set)\s+)*` + quoted + `\s*\(`)
+
+	var bodies [][2]int
+	last := 0
+	for _, match := range header.FindAllIndex(src, -1) {
+		if match[0] < last {
+			continue
+		}
+		paramsEnd := matchJSDelimiter(src, match[1]-1)
+		if paramsEnd < 0 {
+			continue
+		}
+		i := skipJSSpace(src, paramsEnd+1)
+		if bytes.HasPrefix(src[i:], []byte("=>")) {
+			i = skipJSSpace(src, i+2)
+		}
+		if i >= len(src) || src[i] != '{' {
+			continue
+		}
+		bodyEnd := matchJSDelimiter(src, i)
+		if bodyEnd < 0 {
+			continue
+		}
+		bodies = append(bodies, [2]int{i + 1, bodyEnd})
+		last = bodyEnd
+	}
+	return bodies
+}
+
+// skipJSSpace returns the index of the first character from i that is not white space
+func skipJSSpace(src []byte, i int) int {
+	for i < len(src) && (src[i] == ' ' || src[i] == '\t' || src[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// matchJSDelimiter returns the index of the bracket closing the one at open, skipping strings,
+// template literals, comments and regular expressions, or -1 when it is not closed
+func matchJSDelimiter(src []byte, open int) int {
+	closing := map[byte]byte{'(': ')', '{': '}', '[': ']'}[src[open]]
+	depth := 0
+	for i := open; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == src[open]:
+			depth++
+		case c == closing:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		case c == '"' || c == '\'':
+			i = skipJSString(src, i)
+		case c == '`':
+			i = skipJSTemplate(src, i)
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end < 0 {
+				return -1
+			}
+			i += end + 3
+		case c == '/' && isJSRegexStart(src, i):
+			i = skipJSRegex(src, i)
+		}
+		if i < 0 || i >= len(src) {
+			return -1
+		}
+	}
+	return -1
+}
+
+// skipJSString returns the index of the quote closing the string starting at i
+func skipJSString(src []byte, i int) int {
+	quote := src[i]
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			if bytes.HasPrefix(src[i+1:], []byte("\r\n")) {
+				i++
+			}
+			i++
+		case quote:
+			return i
+		case '\n':
+			return -1
+		}
+	}
+	return -1
+}
+
+// skipJSTemplate returns the index of the backtick closing the template literal starting at i
+func skipJSTemplate(src []byte, i int) int {
+	for i++; i < len(src); i++ {
+		switch {
+		case src[i] == '\\':
+			i++
+		case src[i] == '`':
+			return i
+		case src[i] == '$' && i+1 < len(src) && src[i+1] == '{':
+			i = matchJSDelimiter(src, i+1)
+			if i < 0 {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+// jsRegexKeywords are the keywords after which a slash starts a regular expression
+var jsRegexKeywords = []string{"return", "typeof", "instanceof", "case", "do", "else", "in", "of", "new", "delete", "void", "throw", "yield", "await"}
+
+// isJSRegexStart tells whether the slash at i starts a regular expression rather than a division
+func isJSRegexStart(src []byte, i int) bool {
+	j := i - 1
+	for j >= 0 && (src[j] == ' ' || src[j] == '\t' || src[j] == '\n' || src[j] == '\r') {
+		j--
+	}
+	if j < 0 {
+		return true
+	}
+	if isJSIdentifierByte(src[j]) {
+		end := j + 1
+		for j >= 0 && isJSIdentifierByte(src[j]) {
+			j--
+		}
+		return slices.Contains(jsRegexKeywords, string(src[j+1:end]))
+	}
+	return strings.IndexByte("(,=:[!&|?{};+-*%<>~^", src[j]) >= 0
+}
+
+// isJSIdentifierByte tells whether a byte can be part of an identifier
+func isJSIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// skipJSRegex returns the index of the slash closing the regular expression starting at i
+func skipJSRegex(src []byte, i int) int {
+	inClass := false
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if !inClass {
+				return i
+			}
+		case '\n':
+			return -1
+		}
+	}
+	return -1
+}
@@ -0,0 +1,421 @@
+package patcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"htpatcher/internal/domain"
+	"htpatcher/internal/domain/rpgmaker"
+	"htpatcher/internal/util"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// sourceCollector walks data files the same way the patchers do and records the texts they would translate
+type sourceCollector struct {
+	file   string
+	config *domain.Config
+	texts  []domain.SourceText
+}
+
+// CollectSourceTexts lists the translatable texts of a data file along with where they were found
+func CollectSourceTexts(filePath string, data []byte, config *domain.Config) ([]domain.SourceText, error) {
+	if config == nil {
+		config = &domain.Config{}
+	}
+	c := &sourceCollector{file: filepath.Base(filePath), config: config}
+
+	var err error
+	switch getDataFileTypeMap(filePath) {
+	case "commonevents":
+		err = c.collectCommonEvents(data)
+	case "map":
+		err = c.collectMap(data)
+	case "system":
+		err = c.collectSystem(data)
+	case "troops":
+		err = c.collectTroops(data)
+	}
+	if err == nil {
+		err = c.collectDataFields(data, dataFieldsOf(filePath, config))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c.texts, nil
+}
+
+// CollectPluginSourceTexts lists the string literals of a plugin source that hold non-ASCII text,
+// the ones worth translating among the identifiers and keys of the code
+func CollectPluginSourceTexts(pluginName string, data []byte) ([]domain.SourceText, error) {
+	literals, err := scanJSLiterals(data)
+	if err != nil {
+		return nil, err
+	}
+	c := &sourceCollector{file: pluginName + ".js"}
+	for _, literal := range literals {
+		if !hasNonASCII(literal.Value) {
+			continue
+		}
+		c.add(literal.Value, fmt.Sprintf("line %d", literal.Line), "")
+	}
+	return c.texts, nil
+}
+
+// CollectPluginParameterTexts lists the texts of plugins.js found at the parameter paths of the plugins to patch
+func CollectPluginParameterTexts(data []byte, pluginsToPatch []domain.PluginToPatch) ([]domain.SourceText, error) {
+	if !slices.ContainsFunc(pluginsToPatch, func(pluginToPatch domain.PluginToPatch) bool {
+		return len(pluginToPatch.ParameterPaths) > 0
+	}) {
+		return nil, nil
+	}
+	start, end, ok := findPluginsArray(data)
+	if !ok {
+		return nil, nil
+	}
+	plugins, err := parsePluginEntries(data, start, end)
+	if err != nil {
+		return nil, err
+	}
+	c := &sourceCollector{file: "plugins.js"}
+	for _, plugin := range plugins {
+		for _, pluginToPatch := range pluginsToPatch {
+			if plugin.Name != pluginToPatch.Plugin || plugin.Parameters == nil || len(pluginToPatch.ParameterPaths) == 0 {
+				continue
+			}
+			parameters, err := util.ParseJSON(plugin.Parameters)
+			if err != nil {
+				return nil, fmt.Errorf("plugin %s: %w", plugin.Name, err)
+			}
+			for _, text := range parameterPathTexts(parameters, pluginToPatch.ParameterPaths) {
+				c.add(text[0], plugin.Name+" "+text[1], "")
+			}
+		}
+	}
+	return c.texts, nil
+}
+
+// hasNonASCII tells whether a text has a character outside ASCII
+func hasNonASCII(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf {
+			return true
+		}
+	}
+	return false
+}
+
+// add records a text if it is not empty
+func (c *sourceCollector) add(text string, location string, speaker string) {
+	c.addInWindow(text, location, speaker, "")
+}
+
+// addInWindow records a text shown in a window of a known size if it is not empty
+func (c *sourceCollector) addInWindow(text string, location string, speaker string, window string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	c.texts = append(c.texts, domain.SourceText{
+		Text:     text,
+		File:     c.file,
+		Location: location,
+		Speaker:  speaker,
+		Window:   window,
+	})
+}
+
+// collectDataFields records the texts of the fields of a data file, located by their label, index and key
+func (c *sourceCollector) collectDataFields(data []byte, fields dataFileFields) error {
+	if len(fields.fields) == 0 {
+		return nil
+	}
+	value, err := util.ParseJSON(data)
+	if err != nil {
+		return err
+	}
+	type fieldText struct {
+		text   string
+		trail  []string
+		window string
+	}
+	var texts []fieldText
+	for _, field := range fields.fields {
+		steps, err := parseParameterPath(field.Path)
+		if err != nil {
+			continue
+		}
+		visitParameterPathAt(value, steps, nil, func(text string, trail []string) string {
+			texts = append(texts, fieldText{text, trail, field.Window})
+			return text
+		})
+	}
+	// List the fields of each entry together, as the database editor shows them
+	slices.SortStableFunc(texts, func(a, b fieldText) int {
+		if len(a.trail) == 0 || len(b.trail) == 0 {
+			return 0
+		}
+		indexA, errA := strconv.Atoi(a.trail[0])
+		indexB, errB := strconv.Atoi(b.trail[0])
+		if errA != nil || errB != nil {
+			return 0
+		}
+		return indexA - indexB
+	})
+	for _, text := range texts {
+		c.addInWindow(text.text, strings.Join(append([]string{fields.label}, text.trail...), " "), "", text.window)
+	}
+	return nil
+}
+
+func (c *sourceCollector) collectCommonEvents(data []byte) error {
+	var commonEvents rpgmaker.CommonEventsData
+	if err := json.Unmarshal(data, &commonEvents); err != nil {
+		return err
+	}
+	for _, commonEvent := range commonEvents {
+		if commonEvent == nil {
+			continue
+		}
+		c.collectCommands(commonEvent.List, fmt.Sprintf("Common event %d %s", commonEvent.ID, commonEvent.Name))
+	}
+	return nil
+}
+
+func (c *sourceCollector) collectMap(data []byte) error {
+	var mapData rpgmaker.MapData
+	if err := json.Unmarshal(data, &mapData); err != nil {
+		return err
+	}
+	c.add(mapData.DisplayName, "Display name", "")
+	for _, event := range mapData.Events {
+		if event == nil {
+			continue
+		}
+		for i := range event.Pages {
+			c.collectCommands(event.Pages[i].List, fmt.Sprintf("EV%03d %s, page %d", event.ID, event.Name, i+1))
+		}
+	}
+	return nil
+}
+
+func (c *sourceCollector) collectSystem(data []byte) error {
+	var system rpgmaker.System
+	if err := json.Unmarshal(data, &system); err != nil {
+		return err
+	}
+
+	lists := []struct {
+		name  string
+		texts []string
+	}{
+		{"Armor type", system.ArmorTypes},
+		{"Element", system.Elements},
+		{"Equip type", system.EquipTypes},
+		{"Skill type", system.SkillTypes},
+		{"Weapon type", system.WeaponTypes},
+		{"Switch", system.Switches},
+		{"Variable", system.Variables},
+		{"Basic term", system.Terms.Basic},
+		{"Parameter term", system.Terms.Params},
+	}
+	for _, list := range lists {
+		for i, text := range list.texts {
+			c.add(text, fmt.Sprintf("%s %d", list.name, i), "")
+		}
+	}
+	for i, command := range system.Terms.Commands {
+		if command != nil {
+			c.add(*command, fmt.Sprintf("Command term %d", i), "")
+		}
+	}
+
+	// Every string field of the messages is patched, including the ones only MZ has
+	messages := reflect.ValueOf(system.Terms.Messages)
+	for i := 0; i < messages.NumField(); i++ {
+		field := messages.Field(i)
+		if field.Kind() == reflect.Pointer && !field.IsNil() {
+			field = field.Elem()
+		}
+		if field.Kind() == reflect.String {
+			c.add(field.String(), "Message "+messages.Type().Field(i).Name, "")
+		}
+	}
+	return nil
+}
+
+func (c *sourceCollector) collectTroops(data []byte) error {
+	var troops rpgmaker.TroopsData
+	if err := json.Unmarshal(data, &troops); err != nil {
+		return err
+	}
+	for _, troop := range troops {
+		if troop == nil {
+			continue
+		}
+		c.add(troop.Name, fmt.Sprintf("Troop %d name", troop.ID), "")
+		for i := range troop.Pages {
+			c.collectCommands(troop.Pages[i].List, fmt.Sprintf("Troop %d %s, page %d", troop.ID, troop.Name, i+1))
+		}
+	}
+	return nil
+}
+
+// collectCommands mirrors patchCommands, keeping track of the current speaker
+func (c *sourceCollector) collectCommands(commands []*rpgmaker.EventCommand, location string) {
+	speaker := ""
+	window := domain.SourceWindowMessage
+
+	for commandIndex := 0; commandIndex < len(commands); commandIndex++ {
+		command := commands[commandIndex]
+		if len(command.Parameters) == 0 {
+			continue
+		}
+
+		switch command.Code {
+		case 101:
+			// The speaker is the name in param 4 if present, otherwise the face image in param 0
+			speaker = ""
+			window = domain.SourceWindowMessage
+			if face, ok := command.Parameters[0].(string); ok && face != "" {
+				window = domain.SourceWindowMessageFace
+			}
+			if len(command.Parameters) > 4 {
+				if name, ok := command.Parameters[4].(string); ok {
+					speaker = name
+					c.add(name, location+", speaker name", "")
+				}
+			}
+			if face, ok := command.Parameters[0].(string); ok && speaker == "" && face != "" {
+				speaker = "[" + face + "]"
+			}
+		case 401:
+			fullText := ""
+			for commandIndex < len(commands) && commands[commandIndex].Code == 401 {
+				if text, ok := firstParameter(commands[commandIndex]).(string); ok {
+					fullText += text
+				}
+				commandIndex++
+			}
+			commandIndex--
+			c.addInWindow(fullText, location, speaker, window)
+		case 405:
+			if text, ok := command.Parameters[0].(string); ok {
+				c.add(text, location+", scrolling text", "")
+			}
+		case 102:
+			if choices, ok := command.Parameters[0].([]any); ok {
+				for _, choice := range choices {
+					if choice, ok := choice.(string); ok {
+						c.add(choice, location+", choice", "")
+					}
+				}
+			}
+		case 408:
+			if description, ok := command.Parameters[0].(string); ok {
+				c.add(description, location+", comment", "")
+			}
+		case 122:
+			if len(command.Parameters) > 4 {
+				varID, ok := command.Parameters[0].(float64)
+				if !ok || !slices.Contains(c.config.VariablesToPatch, int(varID)) {
+					continue
+				}
+				if value, ok := command.Parameters[4].(string); ok {
+					for _, text := range variableValueTexts(value) {
+						c.add(text, fmt.Sprintf("%s, variable %d", location, int(varID)), "")
+					}
+				}
+			}
+		case 355:
+			fullScript, _ := command.Parameters[0].(string)
+			for commandIndex+1 < len(commands) && commands[commandIndex+1].Code == 655 {
+				if text, ok := firstParameter(commands[commandIndex+1]).(string); ok {
+					fullScript += "\n" + text
+				}
+				commandIndex++
+			}
+			c.add(fullScript, location+", script", "")
+		case 357:
+			if len(command.Parameters) <= 3 {
+				continue
+			}
+			plugin, _ := command.Parameters[0].(string)
+			function, _ := command.Parameters[1].(string)
+			for _, parameter := range c.config.ParametersToPatch {
+				if parameter.Plugin != plugin || parameter.Function != function {
+					continue
+				}
+				commandLocation := fmt.Sprintf("%s, plugin command %s.%s", location, plugin, function)
+				switch {
+				case len(parameter.ParameterPathsToPatch) > 0:
+					for _, text := range parameterPathTexts(command.Parameters[3], parameter.ParameterPathsToPatch) {
+						c.add(text[0], commandLocation+" "+text[1], "")
+					}
+				case parameter.RootType == "string":
+					if options, ok := command.Parameters[3].(string); ok {
+						c.add(options, commandLocation, "")
+					}
+				case parameter.Recursive:
+					for _, text := range parameterValueTexts(command.Parameters[3]) {
+						c.add(text, commandLocation, "")
+					}
+				}
+			}
+		}
+	}
+}
+
+// firstParameter returns the first parameter of a command, or nil if it has none
+func firstParameter(command *rpgmaker.EventCommand) any {
+	if len(command.Parameters) == 0 {
+		return nil
+	}
+	return command.Parameters[0]
+}
+
+// variableValueTexts lists the strings patchVariableValue would look up
+func variableValueTexts(value string) []string {
+	if strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\";") && len(value) >= 3 {
+		return []string{value[1 : len(value)-2]}
+	}
+	if len(value) < 2 {
+		return nil
+	}
+	if strings.HasPrefix(value, "\"") && strings.HasSuffix(value, "\"") {
+		return []string{value[1 : len(value)-1]}
+	}
+	if strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		s := value[1 : len(value)-1]
+		var jsonArray []any
+		if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") && json.Unmarshal([]byte(s), &jsonArray) == nil {
+			return parameterValueTexts(jsonArray)
+		}
+		return []string{s}
+	}
+	return nil
+}
+
+// parameterValueTexts lists the strings patchParameterValue would look up
+func parameterValueTexts(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		texts := []string{}
+		for _, item := range v {
+			texts = append(texts, parameterValueTexts(item)...)
+		}
+		return texts
+	case *util.OrderedMap:
+		texts := []string{}
+		for _, key := range v.Keys {
+			texts = append(texts, parameterValueTexts(v.Values[key])...)
+		}
+		return texts
+	default:
+		return nil
+	}
+}
@@ -3,14 +3,18 @@ package repository
 import (
 	"archive/zip"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"htpatcher/internal/domain"
 	"io"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
@@ -22,9 +26,41 @@ func NewPatchRepository() *PatchRepository {
 	return &PatchRepository{}
 }
 
-// Open opens a patch file and returns a zip reader
+// Open opens a patch file and returns a zip reader, refusing patches whose entries are ambiguous
 func (r *PatchRepository) Open(path string) (*zip.ReadCloser, error) {
-	return zip.OpenReader(path)
+	zipReader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := patchEntries(zipReader); err != nil {
+		zipReader.Close()
+		return nil, err
+	}
+	return zipReader, nil
+}
+
+// patchEntries returns the files of a patch by name. Every reader goes through it so that what is read
+// is what the signature covers: duplicate names and entries that are neither files nor folders, such as
+// symlinks, are refused as they could hide unsigned content behind a signed file.
+func patchEntries(zipReader *zip.ReadCloser) (map[string]*zip.File, error) {
+	entries := make(map[string]*zip.File)
+	names := make(map[string]bool)
+	for _, f := range zipReader.File {
+		if names[f.Name] {
+			return nil, errors.New("duplicate entry " + f.Name)
+		}
+		names[f.Name] = true
+
+		mode := f.Mode()
+		switch {
+		case mode.IsRegular():
+			entries[f.Name] = f
+		case mode.IsDir() && strings.HasSuffix(f.Name, "/"):
+		default:
+			return nil, errors.New("unsupported entry " + f.Name)
+		}
+	}
+	return entries, nil
 }
 
 // ReadDictionary reads the translation dictionary from a patch
@@ -36,6 +72,22 @@ func (r *PatchRepository) ReadDictionary(zipReader *zip.ReadCloser) (map[string]
 	return *dictionary, nil
 }
 
+// ReadGlossary reads the glossary of a patch, which is optional
+func (r *PatchRepository) ReadGlossary(zipReader *zip.ReadCloser) (map[string]string, error) {
+	entries, err := patchEntries(zipReader)
+	if err != nil {
+		return nil, err
+	}
+	if entries["glossary.json"] == nil {
+		return map[string]string{}, nil
+	}
+	glossary, err := readJSONFromZip[map[string]string](zipReader, "glossary.json")
+	if err != nil {
+		return nil, err
+	}
+	return *glossary, nil
+}
+
 // ReadConfig reads the patch configuration
 func (r *PatchRepository) ReadConfig(zipReader *zip.ReadCloser) (*domain.Config, error) {
 	return readJSONFromZip[domain.Config](zipReader, "config.json")
@@ -43,29 +95,161 @@ func (r *PatchRepository) ReadConfig(zipReader *zip.ReadCloser) (*domain.Config,
 
 // GetAllOverrides lists all override files in the patch
 func (r *PatchRepository) GetAllOverrides(zipReader *zip.ReadCloser) ([]string, error) {
+	entries, err := patchEntries(zipReader)
+	if err != nil {
+		return nil, err
+	}
 	overrides := []string{}
-	for _, f := range zipReader.File {
-		if strings.HasPrefix(f.Name, "overrides/") && f.Mode().IsRegular() {
-			overrides = append(overrides, strings.TrimPrefix(f.Name, "overrides/"))
+	for _, name := range slices.Sorted(maps.Keys(entries)) {
+		if strings.HasPrefix(name, "overrides/") {
+			overrides = append(overrides, strings.TrimPrefix(name, "overrides/"))
 		}
 	}
 	return overrides, nil
 }
 
+// GetOverrideSizes returns the uncompressed size of every override file in the patch
+func (r *PatchRepository) GetOverrideSizes(zipReader *zip.ReadCloser) (map[string]int64, error) {
+	entries, err := patchEntries(zipReader)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int64)
+	for name, f := range entries {
+		if strings.HasPrefix(name, "overrides/") {
+			sizes[strings.TrimPrefix(name, "overrides/")] = int64(f.UncompressedSize64)
+		}
+	}
+	return sizes, nil
+}
+
 // ReadFileFromZip reads a specific file from the patch
 func (r *PatchRepository) ReadFileFromZip(zipReader *zip.ReadCloser, path string) ([]byte, error) {
 	path = strings.ReplaceAll(path, "\\", "/")
-	for _, f := range zipReader.File {
-		if f.Name == path {
-			rc, err := f.Open()
+	entries, err := patchEntries(zipReader)
+	if err != nil {
+		return nil, err
+	}
+	f := entries[path]
+	if f == nil {
+		return nil, errors.New("file " + path + " not found")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// Files holding the signature of a patch
+const (
+	manifestName  = "manifest.json"
+	signatureName = "manifest.sig"
+)
+
+// WriteFileToZip adds or replaces a file in a patch, copying every other entry unchanged
+func (r *PatchRepository) WriteFileToZip(path string, name string, data []byte) error {
+	return r.WriteFilesToZip(path, map[string][]byte{name: data})
+}
+
+// WriteFilesToZip adds or replaces files in a patch, copying every other entry unchanged.
+// Changing a signed patch invalidates its signature, so the signature is dropped unless it is being written.
+func (r *PatchRepository) WriteFilesToZip(path string, files map[string][]byte) error {
+	zipReader, err := r.Open(path)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file next to the patch so the rename does not cross devices
+	tmpPath := path + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		zipReader.Close()
+		return err
+	}
+
+	_, signing := files[signatureName]
+	err = func() error {
+		zipWriter := zip.NewWriter(out)
+		for _, f := range zipReader.File {
+			if _, ok := files[f.Name]; ok {
+				continue
+			}
+			if !signing && (f.Name == manifestName || f.Name == signatureName) {
+				continue
+			}
+			if err := zipWriter.Copy(f); err != nil {
+				return err
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(files)) {
+			w, err := zipWriter.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
 			if err != nil {
-				return nil, err
+				return err
 			}
-			defer rc.Close()
-			return io.ReadAll(rc)
+			if _, err := w.Write(files[name]); err != nil {
+				return err
+			}
+		}
+		return zipWriter.Close()
+	}()
+	zipReader.Close()
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// HashFiles returns the SHA-256 hash of every file of a patch except its signature
+func (r *PatchRepository) HashFiles(zipReader *zip.ReadCloser) (map[string]string, error) {
+	entries, err := patchEntries(zipReader)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string)
+	for name, f := range entries {
+		if name == manifestName || name == signatureName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
 		}
+		h := sha256.New()
+		_, err = io.Copy(h, rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		hashes[name] = hex.EncodeToString(h.Sum(nil))
 	}
-	return nil, errors.New("file " + path + " not found")
+	return hashes, nil
+}
+
+// ReadSignature reads the signed manifest of a patch and its signature, both nil when the patch is unsigned
+func (r *PatchRepository) ReadSignature(zipReader *zip.ReadCloser) ([]byte, []byte, error) {
+	entries, err := patchEntries(zipReader)
+	if err != nil {
+		return nil, nil, err
+	}
+	var manifest, signature []byte
+	if entries[manifestName] != nil {
+		if manifest, err = r.ReadFileFromZip(zipReader, manifestName); err != nil {
+			return nil, nil, err
+		}
+	}
+	if entries[signatureName] != nil {
+		if signature, err = r.ReadFileFromZip(zipReader, signatureName); err != nil {
+			return nil, nil, err
+		}
+	}
+	return manifest, signature, nil
 }
 
 // Download downloads a patch from the download service
@@ -145,24 +329,27 @@ type Upload struct {
 
 // readJSONFromZip is a generic helper to read and unmarshal JSON from a zip file
 func readJSONFromZip[T any](zipReader *zip.ReadCloser, name string) (*T, error) {
-	for _, f := range zipReader.File {
-		if f.Name == name {
-			rc, err := f.Open()
-			if err != nil {
-				return nil, err
-			}
-			defer rc.Close()
-
-			b, err := io.ReadAll(rc)
-			if err != nil {
-				return nil, err
-			}
+	entries, err := patchEntries(zipReader)
+	if err != nil {
+		return nil, err
+	}
+	f := entries[name]
+	if f == nil {
+		return nil, errors.New(name + " not found")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
 
-			var v T
-			return &v, json.Unmarshal(b, &v)
-		}
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.New(name + " not found")
+
+	var v T
+	return &v, json.Unmarshal(b, &v)
 }
 
 
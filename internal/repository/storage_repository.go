@@ -27,6 +27,21 @@ func (r *StorageRepository) Load() (*domain.PersistentData, error) {
 		return &domain.PersistentData{
 			LocatedGames: []domain.LocatedGame{},
 			GamesPerRow:  3,
+			BackupSettings: domain.BackupSettings{
+				Mode:   domain.BackupModeFolder,
+				Format: domain.BackupFormatZip,
+			},
+			TranslatorSettings: domain.TranslatorSettings{
+				SourceLanguage: "ja",
+				BatchSize:      50,
+			},
+			SignatureSettings: domain.SignatureSettings{
+				Policy:      domain.SignaturePolicyWarn,
+				TrustedKeys: []domain.TrustedKey{},
+			},
+			OverrideSettings: domain.OverrideSettings{
+				AllowedPaths: []string{},
+			},
 		}, nil
 	}
 
@@ -45,6 +60,33 @@ func (r *StorageRepository) Load() (*domain.PersistentData, error) {
 	if persistentData.GamesPerRow == 0 {
 		persistentData.GamesPerRow = 3
 	}
+
+	// Set default backup settings if not present (backward compatibility)
+	if persistentData.BackupSettings.Mode == "" {
+		persistentData.BackupSettings.Mode = domain.BackupModeFolder
+	}
+	if persistentData.BackupSettings.Format == "" {
+		persistentData.BackupSettings.Format = domain.BackupFormatZip
+	}
+
+	// Set default translator settings if not present (backward compatibility)
+	if persistentData.TranslatorSettings.SourceLanguage == "" {
+		persistentData.TranslatorSettings.SourceLanguage = "ja"
+	}
+	if persistentData.TranslatorSettings.BatchSize <= 0 {
+		persistentData.TranslatorSettings.BatchSize = 50
+	}
+
+	// Set default signature settings if not present (backward compatibility)
+	if persistentData.SignatureSettings.Policy == "" {
+		persistentData.SignatureSettings.Policy = domain.SignaturePolicyWarn
+	}
+	if persistentData.SignatureSettings.TrustedKeys == nil {
+		persistentData.SignatureSettings.TrustedKeys = []domain.TrustedKey{}
+	}
+	if persistentData.OverrideSettings.AllowedPaths == nil {
+		persistentData.OverrideSettings.AllowedPaths = []string{}
+	}
 	
 	return &persistentData, nil
 }
@@ -1,43 +1,571 @@
 package service
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"htpatcher/internal/domain"
 	"htpatcher/internal/domain/rpgmaker"
+	"htpatcher/internal/patcher"
 	"htpatcher/internal/util"
 	"io"
 	"os"
 	"path/filepath"
 	"slices"
+	"time"
 )
 
+const (
+	backupDirName      = ".backup"
+	backupFilesDirName = "files"
+	backupManifestName = "manifest.json"
+	patchSummaryName   = "patch-summary.json"
+)
+
+// BackupLocator resolves the backup settings and the collection entry of a game
+type BackupLocator interface {
+	GetBackupSettings() domain.BackupSettings
+	FindGameByDir(gameDir string) (*domain.LocatedGame, error)
+}
+
 // BackupService handles backup and restore operations
+//
+// Backups are stored as numbered generations, either inside the .backup folder of the game
+// or as compressed archives in the backup root. Each generation holds the files as they were
+// right before a patch was applied, together with a manifest recording their hashes and the
+// patch that was applied.
 type BackupService struct {
-	logger Logger
+	locator BackupLocator
+	logger  Logger
 }
 
 // NewBackupService creates a new backup service
-func NewBackupService(logger Logger) *BackupService {
-	return &BackupService{logger: logger}
+func NewBackupService(locator BackupLocator, logger Logger) *BackupService {
+	return &BackupService{locator: locator, logger: logger}
+}
+
+// BackupGameData creates a new backup generation of game data before patching
+func (s *BackupService) BackupGameData(gameInfo *domain.GameInfo, patchInfo *domain.PatchInfo) (*domain.BackupManifest, error) {
+	filesToBackup, err := listFilesToBackup(gameInfo, patchInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info(fmt.Sprintf("Found %d files to backup", len(filesToBackup)))
+
+	store, manifests, err := s.loadManifestsForWrite(gameInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	generation := 1
+	if len(manifests) > 0 {
+		generation = manifests[len(manifests)-1].Generation + 1
+	}
+
+	manifest := &domain.BackupManifest{
+		Generation: generation,
+		CreatedAt:  time.Now().UTC().Format(time.RFC3339),
+		Patch:      describePatch(patchInfo),
+	}
+
+	existingFiles := []string{}
+	for _, file := range filesToBackup {
+		hash, size, err := util.HashFile(filepath.Join(gameInfo.GameDir, file))
+		if os.IsNotExist(err) {
+			// The patch creates this file, record it and its missing directories so restore can remove them
+			manifest.CreatedFiles = append(manifest.CreatedFiles, filepath.ToSlash(file))
+			for dir := filepath.Dir(file); dir != "."; dir = filepath.Dir(dir) {
+				if _, err := os.Stat(filepath.Join(gameInfo.GameDir, dir)); err == nil {
+					break
+				}
+				if !slices.Contains(manifest.CreatedDirs, filepath.ToSlash(dir)) {
+					manifest.CreatedDirs = append(manifest.CreatedDirs, filepath.ToSlash(dir))
+				}
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		existingFiles = append(existingFiles, file)
+		manifest.Files = append(manifest.Files, domain.BackupFile{Path: filepath.ToSlash(file), SHA256: hash, Size: size})
+	}
+
+	// Deepest directories first so they are emptied before their parents
+	slices.SortFunc(manifest.CreatedDirs, func(a, b string) int { return len(b) - len(a) })
+
+	if err := store.WriteFiles(generation, gameInfo.GameDir, existingFiles); err != nil {
+		store.Remove(generation)
+		return nil, err
+	}
+	if err := store.WriteManifest(manifest); err != nil {
+		store.Remove(generation)
+		return nil, err
+	}
+
+	s.logger.Info(fmt.Sprintf("Backed up %d files to generation %d in %s", len(manifest.Files), generation, store.Location()))
+	if len(manifest.CreatedFiles) > 0 {
+		s.logger.Info(fmt.Sprintf("Recorded %d files created by the patch", len(manifest.CreatedFiles)))
+	}
+	return manifest, nil
+}
+
+// RecordPatchedFiles stores the hashes of the files written by the patch in a backup generation
+func (s *BackupService) RecordPatchedFiles(gameInfo *domain.GameInfo, generation int) error {
+	store, manifest, err := s.getManifest(gameInfo, generation)
+	if err != nil {
+		return err
+	}
+
+	summaryData, err := os.ReadFile(filepath.Join(gameInfo.GameDir, patchSummaryName))
+	if err != nil {
+		return err
+	}
+	var patchSummary domain.PatchSummary
+	if err := json.Unmarshal(summaryData, &patchSummary); err != nil {
+		return err
+	}
+
+	manifest.PatchedAt = patchSummary.PatchedAt
+	manifest.PatchedFiles = nil
+	seen := []string{}
+	for _, relPath := range patchSummary.PatchedFiles {
+		relPath = filepath.ToSlash(relPath)
+		if slices.Contains(seen, relPath) {
+			continue
+		}
+		seen = append(seen, relPath)
+		hash, size, err := util.HashFile(filepath.Join(gameInfo.GameDir, filepath.FromSlash(relPath)))
+		if err != nil {
+			return err
+		}
+		manifest.PatchedFiles = append(manifest.PatchedFiles, domain.BackupFile{Path: relPath, SHA256: hash, Size: size})
+	}
+
+	return store.WriteManifest(manifest)
+}
+
+// ListBackups returns the manifests of all backup generations, oldest first
+func (s *BackupService) ListBackups(gameInfo *domain.GameInfo) ([]domain.BackupManifest, error) {
+	if len(legacyBackupEntries(gameInfo)) > 0 {
+		s.logger.Warn("Found a backup made by an older version, it becomes generation 1 the next time a backup is made or restored")
+	}
+	_, manifests, err := s.loadManifests(gameInfo)
+	return manifests, err
+}
+
+// RestoreBackup restores the game to its state before the oldest backup generation
+func (s *BackupService) RestoreBackup(gameInfo *domain.GameInfo) error {
+	_, manifests, err := s.loadManifestsForWrite(gameInfo)
+	if err != nil {
+		return err
+	}
+	if len(manifests) == 0 {
+		return errors.New("backup folder not found")
+	}
+	return s.RestoreGeneration(gameInfo, manifests[0].Generation)
+}
+
+// RestoreGeneration restores the game to its state before the given generation was patched.
+// Newer generations are rolled back first, then the restored generations are removed.
+// The backup is kept if any file fails to restore.
+func (s *BackupService) RestoreGeneration(gameInfo *domain.GameInfo, generation int) error {
+	return s.restore(gameInfo, generation, nil)
+}
+
+// RestoreFiles restores individual files to their state before the given generation was patched.
+// The backup generations are kept since the rest of the game stays patched.
+func (s *BackupService) RestoreFiles(gameInfo *domain.GameInfo, generation int, files []string) error {
+	if len(files) == 0 {
+		return errors.New("no files to restore")
+	}
+	return s.restore(gameInfo, generation, files)
+}
+
+// VerifyBackup checks that the files stored in a backup generation match their recorded hashes
+func (s *BackupService) VerifyBackup(gameInfo *domain.GameInfo, generation int) (*domain.BackupVerification, error) {
+	store, manifest, err := s.getManifest(gameInfo, generation)
+	if err != nil {
+		return nil, err
+	}
+	return verifyGeneration(store, manifest)
+}
+
+// VerifyPatchedFiles checks that the live game files still match what the latest patch wrote
+func (s *BackupService) VerifyPatchedFiles(gameInfo *domain.GameInfo) (*domain.BackupVerification, error) {
+	_, manifests, err := s.loadManifests(gameInfo)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(manifests) - 1; i >= 0; i-- {
+		if manifests[i].PatchedAt != "" {
+			return compareHashes(manifests[i].Generation, manifests[i].PatchedFiles, func(file domain.BackupFile) (string, error) {
+				hash, _, err := util.HashFile(filepath.Join(gameInfo.GameDir, filepath.FromSlash(file.Path)))
+				return hash, err
+			})
+		}
+	}
+	return nil, errors.New("no patched files recorded in backup")
+}
+
+// ReadOriginalFiles calls fn with the content of every backed up file as it was before the game was first patched.
+// Files that did not exist before a patch created them are reported with a nil reader.
+func (s *BackupService) ReadOriginalFiles(gameInfo *domain.GameInfo, fn func(relPath string, r io.Reader) error) error {
+	store, manifests, err := s.loadManifests(gameInfo)
+	if err != nil {
+		return err
+	}
+	if len(manifests) == 0 {
+		return errors.New("backup folder not found")
+	}
+
+	// The oldest generation holding a file has its original content
+	seen := make(map[string]bool)
+	for _, manifest := range manifests {
+		err := store.ReadFiles(manifest.Generation, func(relPath string, r io.Reader) error {
+			if seen[relPath] || !hasBackupFile(manifest, relPath) {
+				return nil
+			}
+			seen[relPath] = true
+			return fn(relPath, r)
+		})
+		if err != nil {
+			return err
+		}
+		for _, relPath := range manifest.CreatedFiles {
+			if seen[relPath] {
+				continue
+			}
+			seen[relPath] = true
+			if err := fn(relPath, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// PruneBackups removes the given backup generations.
+// A pruned generation can no longer be used to undo the patch it was taken for.
+func (s *BackupService) PruneBackups(gameInfo *domain.GameInfo, generations []int) error {
+	store, manifests, err := s.loadManifestsForWrite(gameInfo)
+	if err != nil {
+		return err
+	}
+	for _, generation := range generations {
+		if !slices.ContainsFunc(manifests, func(m domain.BackupManifest) bool { return m.Generation == generation }) {
+			return fmt.Errorf("backup generation %d not found", generation)
+		}
+	}
+	for _, generation := range generations {
+		if err := store.Remove(generation); err != nil {
+			return err
+		}
+		s.logger.Info(fmt.Sprintf("Pruned backup generation %d", generation))
+	}
+	return store.Cleanup()
+}
+
+// restore rolls back generations from the newest down to the given one.
+// When files is nil every file is restored and the restored generations are removed,
+// otherwise only the listed files are restored and the generations are kept.
+func (s *BackupService) restore(gameInfo *domain.GameInfo, generation int, files []string) error {
+	store, manifests, err := s.loadManifestsForWrite(gameInfo)
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(manifests, func(m domain.BackupManifest) bool { return m.Generation == generation }) {
+		return fmt.Errorf("backup generation %d not found", generation)
+	}
+
+	toRestore := []domain.BackupManifest{}
+	for i := len(manifests) - 1; i >= 0 && manifests[i].Generation >= generation; i-- {
+		toRestore = append(toRestore, manifests[i])
+	}
+
+	partial := files != nil
+	for i := range files {
+		files[i] = filepath.ToSlash(files[i])
+		if !slices.ContainsFunc(toRestore, func(m domain.BackupManifest) bool {
+			return hasBackupFile(m, files[i]) || slices.Contains(m.CreatedFiles, files[i])
+		}) {
+			return fmt.Errorf("file %s not found in backup", files[i])
+		}
+	}
+
+	// Verify the backup before touching the game so a damaged backup never overwrites anything
+	for _, manifest := range toRestore {
+		s.logger.Info(fmt.Sprintf("Verifying backup generation %d", manifest.Generation))
+		verification, err := verifyGeneration(store, &manifest)
+		if err != nil {
+			return err
+		}
+		for _, path := range slices.Concat(verification.Modified, verification.Missing) {
+			if !partial || slices.Contains(files, path) {
+				s.logger.Error(fmt.Sprintf("Backup file %s of generation %d is damaged", path, manifest.Generation))
+				return fmt.Errorf("backup generation %d is damaged", manifest.Generation)
+			}
+		}
+	}
+
+	s.logger.Info(fmt.Sprintf("Restoring backup from %s to %s", store.Location(), gameInfo.GameDir))
+
+	// Roll back from the newest generation down to the requested one
+	restoredFiles := 0
+	failedFiles := []string{}
+	for _, manifest := range toRestore {
+		s.logger.Info(fmt.Sprintf("Restoring backup generation %d", manifest.Generation))
+
+		err := store.ReadFiles(manifest.Generation, func(relPath string, r io.Reader) error {
+			if !hasBackupFile(manifest, relPath) || (partial && !slices.Contains(files, relPath)) {
+				return nil
+			}
+			if err := restoreFile(gameInfo.GameDir, manifest, relPath, r); err != nil {
+				s.logger.Error(fmt.Sprintf("Failed to restore file %s: %v", relPath, err))
+				if !slices.Contains(failedFiles, relPath) {
+					failedFiles = append(failedFiles, relPath)
+				}
+				return nil
+			}
+			restoredFiles++
+			s.logger.Info(fmt.Sprintf("Restored file %s", relPath))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Remove the files the patch created, then the directories it created for them
+		for _, relPath := range manifest.CreatedFiles {
+			if partial && !slices.Contains(files, relPath) {
+				continue
+			}
+			err := os.Remove(filepath.Join(gameInfo.GameDir, filepath.FromSlash(relPath)))
+			if err != nil && !os.IsNotExist(err) {
+				s.logger.Error(fmt.Sprintf("Failed to remove created file %s: %v", relPath, err))
+				failedFiles = append(failedFiles, relPath)
+				continue
+			}
+			s.logger.Info(fmt.Sprintf("Removed created file %s", relPath))
+		}
+		for _, relPath := range manifest.CreatedDirs {
+			dirPath := filepath.Join(gameInfo.GameDir, filepath.FromSlash(relPath))
+			if entries, err := os.ReadDir(dirPath); err == nil && len(entries) == 0 {
+				os.Remove(dirPath)
+				s.logger.Info(fmt.Sprintf("Removed created directory %s", relPath))
+			}
+		}
+	}
+
+	s.logger.Info(fmt.Sprintf("Restored %d files from backup", restoredFiles))
+
+	if len(failedFiles) > 0 {
+		s.logger.Warn(fmt.Sprintf("Kept backup since %d files failed to restore", len(failedFiles)))
+		return fmt.Errorf("failed to restore %d files", len(failedFiles))
+	}
+	if partial {
+		return nil
+	}
+
+	// Remove the restored generations, they no longer describe the game
+	for _, manifest := range toRestore {
+		if err := store.Remove(manifest.Generation); err != nil {
+			return err
+		}
+		s.logger.Info(fmt.Sprintf("Removed backup generation %d", manifest.Generation))
+	}
+
+	// Remove the backup location once no generation is left
+	return store.Cleanup()
+}
+
+// getStore resolves where the backups of a game are stored.
+// A location that already holds generations is kept so a game's backups never get split.
+func (s *BackupService) getStore(gameInfo *domain.GameInfo) (backupStore, error) {
+	folderStore := newFolderBackupStore(filepath.Join(gameInfo.GameDir, backupDirName))
+	if s.locator == nil {
+		return folderStore, nil
+	}
+
+	var archiveStore backupStore
+	if game, err := s.locator.FindGameByDir(gameInfo.GameDir); err == nil {
+		settings := s.locator.GetBackupSettings()
+		root := settings.Root
+		if root == "" {
+			if root, err = util.GetBackupRootDir(); err != nil {
+				return nil, err
+			}
+		}
+		archiveStore = newArchiveBackupStore(filepath.Join(root, game.Id), settings.Format)
+
+		if settings.Mode == domain.BackupModeArchive {
+			if generations, err := folderStore.Generations(); err == nil && len(generations) > 0 {
+				return folderStore, nil
+			}
+			return archiveStore, nil
+		}
+	}
+
+	if archiveStore != nil {
+		if generations, err := archiveStore.Generations(); err == nil && len(generations) > 0 {
+			return archiveStore, nil
+		}
+	}
+	return folderStore, nil
+}
+
+// loadManifestsForWrite is loadManifests for the operations that modify backups.
+// It is the only place a legacy backup is migrated, so reading backups never changes them.
+func (s *BackupService) loadManifestsForWrite(gameInfo *domain.GameInfo) (backupStore, []domain.BackupManifest, error) {
+	if err := s.migrateLegacyBackup(gameInfo); err != nil {
+		return nil, nil, err
+	}
+	return s.loadManifests(gameInfo)
+}
+
+// loadManifests reads the manifests of all backup generations, oldest first
+func (s *BackupService) loadManifests(gameInfo *domain.GameInfo) (backupStore, []domain.BackupManifest, error) {
+	store, err := s.getStore(gameInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	generations, err := store.Generations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	manifests := []domain.BackupManifest{}
+	for _, generation := range generations {
+		manifest, err := store.ReadManifest(generation)
+		if err != nil {
+			s.logger.Warn(fmt.Sprintf("Skipping backup generation %d: %v", generation, err))
+			continue
+		}
+		manifests = append(manifests, *manifest)
+	}
+	return store, manifests, nil
+}
+
+// getManifest reads the manifest of a single backup generation
+func (s *BackupService) getManifest(gameInfo *domain.GameInfo, generation int) (backupStore, *domain.BackupManifest, error) {
+	store, manifests, err := s.loadManifests(gameInfo)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, manifest := range manifests {
+		if manifest.Generation == generation {
+			return store, &manifest, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("backup generation %d not found", generation)
+}
+
+// legacyBackupDirs are the folders a backup made before generations existed holds, relative to the game directory
+var legacyBackupDirs = []string{"data", "js", "img", "www"}
+
+// legacyBackupEntries returns the folders of a backup made before generations existed.
+// Anything else in the backup folder, such as files added by the OS, is not part of it.
+func legacyBackupEntries(gameInfo *domain.GameInfo) []os.DirEntry {
+	entries, err := os.ReadDir(filepath.Join(gameInfo.GameDir, backupDirName))
+	if err != nil {
+		return nil
+	}
+
+	legacyEntries := []os.DirEntry{}
+	for _, entry := range entries {
+		if entry.IsDir() && slices.Contains(legacyBackupDirs, entry.Name()) {
+			legacyEntries = append(legacyEntries, entry)
+		}
+	}
+	return legacyEntries
 }
 
-// BackupGameData creates a backup of game data before patching
-func (s *BackupService) BackupGameData(gameInfo *domain.GameInfo, patchInfo *domain.PatchInfo) error {
+// migrateLegacyBackup moves a backup made before generations existed into generation 1
+func (s *BackupService) migrateLegacyBackup(gameInfo *domain.GameInfo) error {
+	backupPath := filepath.Join(gameInfo.GameDir, backupDirName)
+	legacyEntries := legacyBackupEntries(gameInfo)
+	if len(legacyEntries) == 0 {
+		return nil
+	}
+
+	s.logger.Info("Migrating existing backup to generation 1")
+
+	// Make room for the legacy backup as the oldest generation
+	store := newFolderBackupStore(backupPath)
+	generations, err := store.Generations()
+	if err != nil {
+		return err
+	}
+	for i := len(generations) - 1; i >= 0; i-- {
+		manifest, err := store.ReadManifest(generations[i])
+		if err != nil {
+			return err
+		}
+		manifest.Generation = generations[i] + 1
+		if err := os.Rename(store.generationPath(generations[i]), store.generationPath(manifest.Generation)); err != nil {
+			return err
+		}
+		if err := store.WriteManifest(manifest); err != nil {
+			return err
+		}
+	}
+
+	filesPath := filepath.Join(store.generationPath(1), backupFilesDirName)
+	if err := os.MkdirAll(filesPath, 0755); err != nil {
+		return err
+	}
+	for _, entry := range legacyEntries {
+		if err := os.Rename(filepath.Join(backupPath, entry.Name()), filepath.Join(filesPath, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	// Legacy backups were always taken before the first patch, which created patch-summary.json
+	manifest := &domain.BackupManifest{
+		Generation:   1,
+		CreatedAt:    time.Now().UTC().Format(time.RFC3339),
+		CreatedFiles: []string{patchSummaryName},
+	}
+	err = store.ReadFiles(1, func(relPath string, r io.Reader) error {
+		h := sha256.New()
+		size, err := io.Copy(h, r)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, domain.BackupFile{Path: relPath, SHA256: hex.EncodeToString(h.Sum(nil)), Size: size})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return store.WriteManifest(manifest)
+}
+
+// listFilesToBackup lists the files a patch may modify, relative to the game directory
+func listFilesToBackup(gameInfo *domain.GameInfo, patchInfo *domain.PatchInfo) ([]string, error) {
 	filesToBackup := []string{}
+	addFile := func(relPath string) {
+		if !slices.Contains(filesToBackup, relPath) {
+			filesToBackup = append(filesToBackup, relPath)
+		}
+	}
 
 	// List all json files in the data folder
 	jsonFiles, err := util.ListFilesWithExtension(gameInfo.DataPath, ".json")
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Parse system.json to get title image
 	systemJsonPath := filepath.Join(gameInfo.DataPath, "system.json")
 	systemJson, err := os.ReadFile(systemJsonPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	var systemInfo rpgmaker.System
 	json.Unmarshal(systemJson, &systemInfo)
@@ -47,16 +575,16 @@ func (s *BackupService) BackupGameData(gameInfo *domain.GameInfo, patchInfo *dom
 		titlesPath := filepath.Join(gameInfo.ImgPath, "titles1")
 		filesInTitlesPath, err := os.ReadDir(titlesPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		possibleFilenames := []string{systemInfo.Title1Name + ".png", systemInfo.Title1Name + ".rpgmvp", systemInfo.Title1Name + ".png_"}
 		for _, file := range filesInTitlesPath {
 			if slices.Contains(possibleFilenames, file.Name()) {
 				relPath, err := filepath.Rel(gameInfo.GameDir, filepath.Join(titlesPath, file.Name()))
 				if err != nil {
-					return err
+					return nil, err
 				}
-				filesToBackup = append(filesToBackup, relPath)
+				addFile(relPath)
 			}
 		}
 	}
@@ -64,118 +592,139 @@ func (s *BackupService) BackupGameData(gameInfo *domain.GameInfo, patchInfo *dom
 	for _, jsonFile := range jsonFiles {
 		relPath, err := filepath.Rel(gameInfo.GameDir, jsonFile)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		filesToBackup = append(filesToBackup, relPath)
+		addFile(relPath)
 	}
 
 	if len(patchInfo.Config.PluginsToPatch) > 0 {
 		pluginsJsPath := filepath.Join(gameInfo.JsPath, "plugins.js")
 		pluginsJsRelPath, err := filepath.Rel(gameInfo.GameDir, pluginsJsPath)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		filesToBackup = append(filesToBackup, pluginsJsRelPath)
+		addFile(pluginsJsRelPath)
 		for _, pluginToPatch := range patchInfo.Config.PluginsToPatch {
-			pluginJsPath := filepath.Join(gameInfo.JsPath, "plugins", pluginToPatch.Plugin+".js")
-			pluginJsRelPath, err := filepath.Rel(gameInfo.GameDir, pluginJsPath)
-			if err != nil {
-				return err
+			for _, target := range patcher.PluginFileTargets(gameInfo.JsPath, pluginToPatch) {
+				targetRelPath, err := filepath.Rel(gameInfo.GameDir, target)
+				if err != nil {
+					return nil, err
+				}
+				addFile(targetRelPath)
 			}
-			filesToBackup = append(filesToBackup, pluginJsRelPath)
 		}
 	}
 
-	filesToBackup = append(filesToBackup, patchInfo.Overrides...)
-
-	s.logger.Info(fmt.Sprintf("Found %d files to backup", len(filesToBackup)))
-
-	backupPath := filepath.Join(gameInfo.GameDir, ".backup")
-	os.MkdirAll(backupPath, 0755)
-
-	copiedFiles := 0
-	for _, file := range filesToBackup {
-		srcPath := filepath.Join(gameInfo.GameDir, file)
-		dstPath := filepath.Join(backupPath, file)
-		copied, err := copyFile(srcPath, dstPath, false)
-		if err != nil {
-			return err
-		}
-		if copied {
-			copiedFiles++
-		}
+	for _, override := range patchInfo.Overrides {
+		addFile(filepath.FromSlash(override))
 	}
 
-	s.logger.Info(fmt.Sprintf("Backed up %d unseen files", copiedFiles))
-	return nil
+	// Restoring brings back the summary of a previous patch, or removes the one this patch writes
+	addFile(patchSummaryName)
+
+	return filesToBackup, nil
 }
 
-// RestoreBackup restores a backup
-func (s *BackupService) RestoreBackup(gameInfo *domain.GameInfo) error {
-	backupPath := filepath.Join(gameInfo.GameDir, ".backup")
-	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
-		return errors.New("backup folder not found")
+// describePatch records the patch a backup generation is taken for
+func describePatch(patchInfo *domain.PatchInfo) domain.BackupPatch {
+	backupPatch := domain.BackupPatch{
+		FileName: filepath.Base(patchInfo.PatchPath),
 	}
+	if hash, _, err := util.HashFile(patchInfo.PatchPath); err == nil {
+		backupPatch.SHA256 = hash
+	}
+	if patchInfo.Config != nil {
+		backupPatch.Version = patchInfo.Config.Version
+		backupPatch.Locale = patchInfo.Config.Locale
+	}
+	return backupPatch
+}
 
-	s.logger.Info(fmt.Sprintf("Restoring backup from %s to %s", backupPath, gameInfo.GameDir))
-
-	// Copy recursively from backup path to game path
-	restoredFiles := 0
-	err := filepath.Walk(backupPath, func(srcPath string, info os.FileInfo, err error) error {
-		if err != nil {
+// verifyGeneration checks that the files stored in a generation match the hashes of its manifest
+func verifyGeneration(store backupStore, manifest *domain.BackupManifest) (*domain.BackupVerification, error) {
+	hashes := map[string]string{}
+	err := store.ReadFiles(manifest.Generation, func(relPath string, r io.Reader) error {
+		h := sha256.New()
+		if _, err := io.Copy(h, r); err != nil {
 			return err
 		}
+		hashes[relPath] = hex.EncodeToString(h.Sum(nil))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		// Skip the backup directory itself
-		if srcPath == backupPath {
-			return nil
+	return compareHashes(manifest.Generation, manifest.Files, func(file domain.BackupFile) (string, error) {
+		hash, ok := hashes[file.Path]
+		if !ok {
+			return "", os.ErrNotExist
 		}
+		return hash, nil
+	})
+}
 
-		// Get relative path from backup folder
-		relPath, err := filepath.Rel(backupPath, srcPath)
-		if err != nil {
-			return err
+// restoreFile writes a backed up file into the game directory and checks the result against the manifest
+func restoreFile(gameDir string, manifest domain.BackupManifest, relPath string, r io.Reader) error {
+	dstPath := filepath.Join(gameDir, filepath.FromSlash(relPath))
+	if err := writeFile(dstPath, r); err != nil {
+		return err
+	}
+	hash, _, err := util.HashFile(dstPath)
+	if err != nil {
+		return err
+	}
+	for _, file := range manifest.Files {
+		if file.Path == relPath && file.SHA256 != hash {
+			return errors.New("restored file does not match backup hash")
 		}
+	}
+	return nil
+}
 
-		// Construct destination path
-		dstPath := filepath.Join(gameInfo.GameDir, relPath)
+// hasBackupFile reports whether a generation holds the given file
+func hasBackupFile(manifest domain.BackupManifest, relPath string) bool {
+	return slices.ContainsFunc(manifest.Files, func(f domain.BackupFile) bool { return f.Path == relPath })
+}
 
-		// If it's a directory, create it
-		if info.IsDir() {
-			return os.MkdirAll(dstPath, 0755)
+// compareHashes compares the current hash of each file with its recorded hash
+func compareHashes(generation int, files []domain.BackupFile, currentHash func(file domain.BackupFile) (string, error)) (*domain.BackupVerification, error) {
+	verification := &domain.BackupVerification{
+		Generation: generation,
+		Modified:   []string{},
+		Missing:    []string{},
+	}
+	for _, file := range files {
+		hash, err := currentHash(file)
+		if errors.Is(err, os.ErrNotExist) {
+			verification.Missing = append(verification.Missing, file.Path)
+			continue
 		}
-
-		// If it's a file, copy it with overwrite
-		copied, err := copyFile(srcPath, dstPath, true)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if copied {
-			restoredFiles++
+		if hash != file.SHA256 {
+			verification.Modified = append(verification.Modified, file.Path)
 		}
+	}
+	verification.Valid = len(verification.Modified) == 0 && len(verification.Missing) == 0
+	return verification, nil
+}
 
-		s.logger.Info(fmt.Sprintf("Restored file %s", relPath))
-		return nil
-	})
-
+// writeFile writes the content of r to path, creating parent folders as needed
+func writeFile(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	dst, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-
-	s.logger.Info(fmt.Sprintf("Restored %d files from backup", restoredFiles))
-
-	// Remove the backup folder
-	os.RemoveAll(backupPath)
-	s.logger.Info(fmt.Sprintf("Removed backup folder %s", backupPath))
-
-	// Remove patch-summary.json since the game is no longer patched
-	patchSummaryPath := filepath.Join(gameInfo.GameDir, "patch-summary.json")
-	if _, err := os.Stat(patchSummaryPath); err == nil {
-		os.Remove(patchSummaryPath)
-		s.logger.Info("Removed patch-summary.json")
+	if _, err := io.Copy(dst, r); err != nil {
+		dst.Close()
+		return err
 	}
-
-	return nil
+	return dst.Close()
 }
 
 // copyFile copies a file from src to dst
@@ -188,12 +737,8 @@ func copyFile(srcPath string, dstPath string, overwrite bool) (bool, error) {
 		return false, err
 	}
 	defer src.Close()
-	os.MkdirAll(filepath.Dir(dstPath), 0755)
-	dst, err := os.Create(dstPath)
-	if err != nil {
+	if err := writeFile(dstPath, src); err != nil {
 		return false, err
 	}
-	defer dst.Close()
-	_, err = io.Copy(dst, src)
-	return true, err
+	return true, nil
 }
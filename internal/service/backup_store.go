@@ -0,0 +1,391 @@
+package service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"htpatcher/internal/domain"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// backupStore persists backup generations
+type backupStore interface {
+	// Generations lists the stored generations, oldest first
+	Generations() ([]int, error)
+	ReadManifest(generation int) (*domain.BackupManifest, error)
+	WriteManifest(manifest *domain.BackupManifest) error
+	// WriteFiles stores the given files of the game directory in a generation
+	WriteFiles(generation int, gameDir string, files []string) error
+	// ReadFiles calls fn for every file stored in a generation
+	ReadFiles(generation int, fn func(relPath string, r io.Reader) error) error
+	Remove(generation int) error
+	// Cleanup removes the store location once no generation is left
+	Cleanup() error
+	Location() string
+}
+
+// folderBackupStore keeps raw copies of the files in a folder, one sub folder per generation
+type folderBackupStore struct {
+	path string
+}
+
+func newFolderBackupStore(path string) *folderBackupStore {
+	return &folderBackupStore{path: path}
+}
+
+func (f *folderBackupStore) Generations() ([]int, error) {
+	entries, err := os.ReadDir(f.path)
+	if os.IsNotExist(err) {
+		return []int{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	generations := []int{}
+	for _, entry := range entries {
+		if generation, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() {
+			generations = append(generations, generation)
+		}
+	}
+	sort.Ints(generations)
+	return generations, nil
+}
+
+func (f *folderBackupStore) ReadManifest(generation int) (*domain.BackupManifest, error) {
+	return readManifestFile(filepath.Join(f.generationPath(generation), backupManifestName))
+}
+
+func (f *folderBackupStore) WriteManifest(manifest *domain.BackupManifest) error {
+	return writeManifestFile(filepath.Join(f.generationPath(manifest.Generation), backupManifestName), manifest)
+}
+
+func (f *folderBackupStore) WriteFiles(generation int, gameDir string, files []string) error {
+	filesPath := filepath.Join(f.generationPath(generation), backupFilesDirName)
+	for _, file := range files {
+		if _, err := copyFile(filepath.Join(gameDir, file), filepath.Join(filesPath, file), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *folderBackupStore) ReadFiles(generation int, fn func(relPath string, r io.Reader) error) error {
+	filesPath := filepath.Join(f.generationPath(generation), backupFilesDirName)
+	return filepath.Walk(filesPath, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && path == filesPath {
+			return nil
+		}
+		if err != nil || info.IsDir() {
+			return err
+		}
+		relPath, err := filepath.Rel(filesPath, path)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return fn(filepath.ToSlash(relPath), file)
+	})
+}
+
+func (f *folderBackupStore) Remove(generation int) error {
+	return os.RemoveAll(f.generationPath(generation))
+}
+
+func (f *folderBackupStore) Cleanup() error {
+	return removeIfEmpty(f.path)
+}
+
+func (f *folderBackupStore) Location() string {
+	return f.path
+}
+
+func (f *folderBackupStore) generationPath(generation int) string {
+	return filepath.Join(f.path, strconv.Itoa(generation))
+}
+
+// archiveBackupStore keeps one compressed archive per generation, with its manifest stored next to it
+type archiveBackupStore struct {
+	path   string
+	format string
+}
+
+func newArchiveBackupStore(path string, format string) *archiveBackupStore {
+	if format != domain.BackupFormatTarZst {
+		format = domain.BackupFormatZip
+	}
+	return &archiveBackupStore{path: path, format: format}
+}
+
+func (a *archiveBackupStore) Generations() ([]int, error) {
+	entries, err := os.ReadDir(a.path)
+	if os.IsNotExist(err) {
+		return []int{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	generations := []int{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if generation, err := strconv.Atoi(name); err == nil {
+			generations = append(generations, generation)
+		}
+	}
+	sort.Ints(generations)
+	return generations, nil
+}
+
+func (a *archiveBackupStore) ReadManifest(generation int) (*domain.BackupManifest, error) {
+	return readManifestFile(a.manifestPath(generation))
+}
+
+func (a *archiveBackupStore) WriteManifest(manifest *domain.BackupManifest) error {
+	return writeManifestFile(a.manifestPath(manifest.Generation), manifest)
+}
+
+func (a *archiveBackupStore) WriteFiles(generation int, gameDir string, files []string) error {
+	if err := os.MkdirAll(a.path, 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a failed backup never leaves a truncated archive behind
+	archivePath := a.archivePath(generation, a.format)
+	tmpPath := archivePath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	switch a.format {
+	case domain.BackupFormatTarZst:
+		err = writeTarZst(out, gameDir, files)
+	default:
+		err = writeZip(out, gameDir, files)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, archivePath)
+}
+
+func (a *archiveBackupStore) ReadFiles(generation int, fn func(relPath string, r io.Reader) error) error {
+	// The format may have changed since the generation was written, so look for both
+	if _, err := os.Stat(a.archivePath(generation, domain.BackupFormatTarZst)); err == nil {
+		return readTarZst(a.archivePath(generation, domain.BackupFormatTarZst), fn)
+	}
+	if _, err := os.Stat(a.archivePath(generation, domain.BackupFormatZip)); err == nil {
+		return readZip(a.archivePath(generation, domain.BackupFormatZip), fn)
+	}
+	return fmt.Errorf("backup archive for generation %d not found", generation)
+}
+
+func (a *archiveBackupStore) Remove(generation int) error {
+	for _, path := range []string{
+		a.archivePath(generation, domain.BackupFormatZip),
+		a.archivePath(generation, domain.BackupFormatTarZst),
+		a.manifestPath(generation),
+	} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *archiveBackupStore) Cleanup() error {
+	return removeIfEmpty(a.path)
+}
+
+func (a *archiveBackupStore) Location() string {
+	return a.path
+}
+
+func (a *archiveBackupStore) manifestPath(generation int) string {
+	return filepath.Join(a.path, strconv.Itoa(generation)+".json")
+}
+
+func (a *archiveBackupStore) archivePath(generation int, format string) string {
+	return filepath.Join(a.path, strconv.Itoa(generation)+"."+format)
+}
+
+// writeZip writes the given files of the game directory to a ZIP archive
+func writeZip(w io.Writer, gameDir string, files []string) error {
+	zipWriter := zip.NewWriter(w)
+	for _, file := range files {
+		err := func() error {
+			src, err := os.Open(filepath.Join(gameDir, file))
+			if err != nil {
+				return err
+			}
+			defer src.Close()
+			dst, err := zipWriter.Create(filepath.ToSlash(file))
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(dst, src)
+			return err
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// readZip calls fn for every file of a ZIP archive
+func readZip(path string, fn func(relPath string, r io.Reader) error) error {
+	zipReader, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zipReader.Close()
+
+	for _, f := range zipReader.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		err := func() error {
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			defer rc.Close()
+			return fn(f.Name, rc)
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTarZst writes the given files of the game directory to a zstd compressed tar archive
+func writeTarZst(w io.Writer, gameDir string, files []string) error {
+	zstdWriter, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	tarWriter := tar.NewWriter(zstdWriter)
+	for _, file := range files {
+		err := func() error {
+			src, err := os.Open(filepath.Join(gameDir, file))
+			if err != nil {
+				return err
+			}
+			defer src.Close()
+			info, err := src.Stat()
+			if err != nil {
+				return err
+			}
+			header := &tar.Header{
+				Name:    filepath.ToSlash(file),
+				Mode:    0644,
+				Size:    info.Size(),
+				ModTime: info.ModTime(),
+			}
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return err
+			}
+			_, err = io.Copy(tarWriter, src)
+			return err
+		}()
+		if err != nil {
+			zstdWriter.Close()
+			return err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		zstdWriter.Close()
+		return err
+	}
+	return zstdWriter.Close()
+}
+
+// readTarZst calls fn for every file of a zstd compressed tar archive
+func readTarZst(path string, fn func(relPath string, r io.Reader) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zstdReader, err := zstd.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer zstdReader.Close()
+
+	tarReader := tar.NewReader(zstdReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(header.Name, tarReader); err != nil {
+			return err
+		}
+	}
+}
+
+// readManifestFile reads a backup manifest
+func readManifestFile(path string) (*domain.BackupManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest domain.BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// writeManifestFile writes a backup manifest
+func writeManifestFile(path string, manifest *domain.BackupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// removeIfEmpty removes a folder if it has no entries left
+func removeIfEmpty(path string) error {
+	entries, err := os.ReadDir(path)
+	if err != nil || len(entries) > 0 {
+		return nil
+	}
+	return os.Remove(path)
+}
@@ -4,7 +4,11 @@ import (
 	"context"
 	"errors"
 	"htpatcher/internal/domain"
+	"htpatcher/internal/util"
+	"net/url"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -137,6 +141,121 @@ func (s *CollectionService) SetGamesPerRow(count int) error {
 	return s.storage.Save(s.data)
 }
 
+// FindGameByDir returns the game of the collection located in a directory
+func (s *CollectionService) FindGameByDir(gameDir string) (*domain.LocatedGame, error) {
+	for i, game := range s.data.LocatedGames {
+		if filepath.Clean(game.GameDir) == filepath.Clean(gameDir) {
+			return &s.data.LocatedGames[i], nil
+		}
+	}
+	return nil, errors.New("game not found")
+}
+
+// GetBackupSettings returns the backup settings
+func (s *CollectionService) GetBackupSettings() domain.BackupSettings {
+	return s.data.BackupSettings
+}
+
+// SetBackupSettings sets the backup settings
+func (s *CollectionService) SetBackupSettings(settings domain.BackupSettings) error {
+	if settings.Mode != domain.BackupModeFolder && settings.Mode != domain.BackupModeArchive {
+		return errors.New("invalid backup mode")
+	}
+	if settings.Format != domain.BackupFormatZip && settings.Format != domain.BackupFormatTarZst {
+		return errors.New("invalid backup format")
+	}
+	s.data.BackupSettings = settings
+	return s.storage.Save(s.data)
+}
+
+// GetTranslatorSettings returns the machine translation settings
+func (s *CollectionService) GetTranslatorSettings() domain.TranslatorSettings {
+	return s.data.TranslatorSettings
+}
+
+// SetTranslatorSettings sets the machine translation settings
+func (s *CollectionService) SetTranslatorSettings(settings domain.TranslatorSettings) error {
+	if settings.Endpoint != "" {
+		endpoint, err := url.Parse(settings.Endpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+			return errors.New("invalid translator endpoint")
+		}
+	}
+	if settings.SourceLanguage == "" {
+		settings.SourceLanguage = "ja"
+	}
+	if settings.BatchSize <= 0 {
+		return errors.New("invalid batch size")
+	}
+	s.data.TranslatorSettings = settings
+	return s.storage.Save(s.data)
+}
+
+// GetSignatureSettings returns the patch signature settings
+func (s *CollectionService) GetSignatureSettings() domain.SignatureSettings {
+	return s.data.SignatureSettings
+}
+
+// SetSignatureSettings sets the patch signature policy and the keys trusted by the user
+func (s *CollectionService) SetSignatureSettings(settings domain.SignatureSettings) error {
+	if settings.Policy != domain.SignaturePolicyWarn && settings.Policy != domain.SignaturePolicyRequire {
+		return errors.New("invalid signature policy")
+	}
+	keys := []domain.TrustedKey{}
+	for _, key := range settings.TrustedKeys {
+		// Embedded keys are not stored
+		if key.Embedded {
+			continue
+		}
+		key.Name = strings.TrimSpace(key.Name)
+		key.PublicKey = strings.TrimSpace(key.PublicKey)
+		if key.Name == "" {
+			return errors.New("trusted key name is required")
+		}
+		if _, err := util.ParsePublicKey(key.PublicKey); err != nil {
+			return errors.New("invalid public key for " + key.Name)
+		}
+		if slices.ContainsFunc(keys, func(other domain.TrustedKey) bool { return other.PublicKey == key.PublicKey }) {
+			return errors.New("key of " + key.Name + " is already trusted")
+		}
+		keys = append(keys, key)
+	}
+	settings.TrustedKeys = keys
+	s.data.SignatureSettings = settings
+	return s.storage.Save(s.data)
+}
+
+// GetOverrideSettings returns the folders patches may write override files to
+func (s *CollectionService) GetOverrideSettings() domain.OverrideSettings {
+	return s.data.OverrideSettings
+}
+
+// SetOverrideSettings sets the folders patches may write override files to
+func (s *CollectionService) SetOverrideSettings(settings domain.OverrideSettings) error {
+	paths := []string{}
+	for _, allowedPath := range settings.AllowedPaths {
+		allowedPath = strings.Trim(strings.ReplaceAll(strings.TrimSpace(allowedPath), "\\", "/"), "/")
+		if allowedPath == "" {
+			continue
+		}
+		if err := util.ValidateRelativePath(allowedPath); err != nil {
+			return errors.New("invalid allowed path: " + err.Error())
+		}
+		paths = append(paths, allowedPath)
+	}
+	settings.AllowedPaths = paths
+	s.data.OverrideSettings = settings
+	return s.storage.Save(s.data)
+}
+
+// SelectBackupRoot opens a dialog to select the folder backup archives are stored in
+func (s *CollectionService) SelectBackupRoot(ctx context.Context) (string, error) {
+	return runtime.OpenDirectoryDialog(ctx, runtime.OpenDialogOptions{
+		Title:                "Select the backup folder",
+		CanCreateDirectories: true,
+	})
+}
+
 // SetGamePinned sets the pinned status of a game
 func (s *CollectionService) SetGamePinned(id string, pinned bool) error {
 	for i, game := range s.data.LocatedGames {
@@ -7,10 +7,12 @@ import (
 	"errors"
 	"fmt"
 	"htpatcher/internal/domain"
+	"htpatcher/internal/util"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -27,25 +29,11 @@ func NewExportService(logger Logger) *ExportService {
 
 // ExportPatchedFiles exports all patched files to a ZIP archive
 func (s *ExportService) ExportPatchedFiles(ctx context.Context, gameDir string, friendlyName string) error {
-	// Read patch summary
-	summaryPath := filepath.Join(gameDir, "patch-summary.json")
-	summaryData, err := os.ReadFile(summaryPath)
+	patchSummary, err := s.readPatchSummary(gameDir)
 	if err != nil {
-		s.logger.Error("Failed to read patch-summary.json - game may not be patched")
-		return errors.New("patch-summary.json not found - game may not be patched")
-	}
-
-	var patchSummary domain.PatchSummary
-	if err := json.Unmarshal(summaryData, &patchSummary); err != nil {
-		s.logger.Error("Failed to parse patch-summary.json")
 		return err
 	}
 
-	if len(patchSummary.PatchedFiles) == 0 {
-		s.logger.Error("No patched files found in patch summary")
-		return errors.New("no patched files found")
-	}
-
 	// Sanitize friendly name for filename
 	safeName := sanitizeFilename(friendlyName)
 	defaultFilename := safeName + "_patched_files.zip"
@@ -124,6 +112,294 @@ func (s *ExportService) ExportPatchedFiles(ctx context.Context, gameDir string,
 	return nil
 }
 
+// Delta patch archive layout
+const (
+	deltaManifestName = "delta-manifest.json"
+	deltaDirName      = "deltas"
+	deltaFilesDirName = "files"
+	deltaExtension    = ".htdelta"
+)
+
+// ExportDeltaPatch exports the patched files as binary deltas against the original game files.
+// readOriginals is called with a callback receiving the original content of every backed up file,
+// or a nil reader for files that did not exist before the game was patched.
+// The resulting archive can only be applied to the exact original files.
+func (s *ExportService) ExportDeltaPatch(ctx context.Context, gameDir string, friendlyName string, readOriginals func(fn func(relPath string, r io.Reader) error) error) error {
+	patchSummary, err := s.readPatchSummary(gameDir)
+	if err != nil {
+		return err
+	}
+
+	patchedFiles := make(map[string]bool, len(patchSummary.PatchedFiles))
+	for _, relPath := range patchSummary.PatchedFiles {
+		patchedFiles[filepath.ToSlash(relPath)] = true
+	}
+
+	// Collect the originals of the patched files from the backup
+	originals := make(map[string][]byte)
+	created := make(map[string]bool)
+	err = readOriginals(func(relPath string, r io.Reader) error {
+		if !patchedFiles[relPath] {
+			return nil
+		}
+		if r == nil {
+			created[relPath] = true
+			return nil
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		originals[relPath] = data
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to read original files from backup - a backup is required for delta export")
+		return err
+	}
+
+	safeName := sanitizeFilename(friendlyName)
+	outputPath, err := runtime.SaveFileDialog(ctx, runtime.SaveDialogOptions{
+		Title:           "Export Delta Patch",
+		DefaultFilename: safeName + "_delta_patch.zip",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "ZIP Archive",
+				Pattern:     "*.zip",
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// User cancelled
+	if outputPath == "" {
+		return nil
+	}
+
+	s.logger.Info("Creating delta patch...")
+	zipFile, err := os.Create(outputPath)
+	if err != nil {
+		s.logger.Error("Failed to create ZIP file")
+		return err
+	}
+	defer zipFile.Close()
+
+	zipWriter := zip.NewWriter(zipFile)
+
+	manifest := domain.DeltaManifest{
+		Version:   1,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Files:     []domain.DeltaFile{},
+	}
+	var originalSize, deltaSize int64
+	for _, relPath := range patchSummary.PatchedFiles {
+		slashPath := filepath.ToSlash(relPath)
+		target, err := os.ReadFile(filepath.Join(gameDir, relPath))
+		if os.IsNotExist(err) {
+			s.logger.Info("Skipping missing file: " + relPath)
+			continue
+		}
+		if err != nil {
+			s.logger.Error("Failed to read file: " + relPath)
+			return err
+		}
+
+		file := domain.DeltaFile{
+			Path:         slashPath,
+			TargetSHA256: util.HashBytes(target),
+			TargetSize:   int64(len(target)),
+		}
+		var entryPath string
+		var entryData []byte
+		if original, ok := originals[slashPath]; ok {
+			file.Action = domain.DeltaActionPatch
+			file.SourceSHA256 = util.HashBytes(original)
+			entryPath = deltaDirName + "/" + slashPath + deltaExtension
+			entryData = util.Diff(original, target)
+		} else if created[slashPath] {
+			file.Action = domain.DeltaActionAdd
+			entryPath = deltaFilesDirName + "/" + slashPath
+			entryData = target
+		} else {
+			s.logger.Error("No original found in backup for: " + relPath)
+			return fmt.Errorf("no original found in backup for %s", relPath)
+		}
+
+		writer, err := zipWriter.Create(entryPath)
+		if err != nil {
+			s.logger.Error("Failed to create ZIP entry: " + relPath)
+			return err
+		}
+		if _, err := writer.Write(entryData); err != nil {
+			s.logger.Error("Failed to write file to ZIP: " + relPath)
+			return err
+		}
+		manifest.Files = append(manifest.Files, file)
+		originalSize += file.TargetSize
+		deltaSize += int64(len(entryData))
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	writer, err := zipWriter.Create(deltaManifestName)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(manifestData); err != nil {
+		return err
+	}
+	// The central directory is only written on close, the archive is unreadable when it fails
+	if err := zipWriter.Close(); err != nil {
+		s.logger.Error("Failed to write ZIP file")
+		return err
+	}
+	if err := zipFile.Close(); err != nil {
+		s.logger.Error("Failed to write ZIP file")
+		return err
+	}
+
+	s.logger.Success(fmt.Sprintf("Exported delta patch for %d files (%d KB instead of %d KB)", len(manifest.Files), deltaSize/1024, originalSize/1024))
+	return nil
+}
+
+// ApplyDeltaPatch applies a delta patch archive to a game.
+// Every file the patch targets is checked against its original hash before anything is written,
+// so the game is left untouched if it does not match the files the patch was made for.
+func (s *ExportService) ApplyDeltaPatch(ctx context.Context, gameDir string) error {
+	archivePath, err := runtime.OpenFileDialog(ctx, runtime.OpenDialogOptions{
+		Title: "Select Delta Patch",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "ZIP Archive",
+				Pattern:     "*.zip",
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// User cancelled
+	if archivePath == "" {
+		return nil
+	}
+
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		s.logger.Error("Failed to open delta patch")
+		return err
+	}
+	defer zipReader.Close()
+
+	entries := make(map[string]*zip.File, len(zipReader.File))
+	for _, f := range zipReader.File {
+		entries[f.Name] = f
+	}
+	readEntry := func(name string) ([]byte, error) {
+		f, ok := entries[name]
+		if !ok {
+			return nil, fmt.Errorf("%s not found in delta patch", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+
+	manifestData, err := readEntry(deltaManifestName)
+	if err != nil {
+		s.logger.Error("Not a delta patch: " + deltaManifestName + " is missing")
+		return err
+	}
+	var manifest domain.DeltaManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		s.logger.Error("Failed to parse " + deltaManifestName)
+		return err
+	}
+
+	// Build every file in memory first so a mismatch leaves the game untouched
+	results := make(map[string][]byte, len(manifest.Files))
+	for _, file := range manifest.Files {
+		if !filepath.IsLocal(filepath.FromSlash(file.Path)) {
+			return fmt.Errorf("invalid path in delta patch: %s", file.Path)
+		}
+		var result []byte
+		switch file.Action {
+		case domain.DeltaActionPatch:
+			original, err := os.ReadFile(filepath.Join(gameDir, filepath.FromSlash(file.Path)))
+			if err != nil {
+				s.logger.Error("Missing original file: " + file.Path)
+				return err
+			}
+			if util.HashBytes(original) != file.SourceSHA256 {
+				s.logger.Error("File does not match the original the patch was made for: " + file.Path)
+				return fmt.Errorf("%s does not match the original the patch was made for", file.Path)
+			}
+			delta, err := readEntry(deltaDirName + "/" + file.Path + deltaExtension)
+			if err != nil {
+				return err
+			}
+			if result, err = util.ApplyDelta(original, delta); err != nil {
+				s.logger.Error("Failed to apply delta: " + file.Path)
+				return err
+			}
+		case domain.DeltaActionAdd:
+			if result, err = readEntry(deltaFilesDirName + "/" + file.Path); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown delta action %q for %s", file.Action, file.Path)
+		}
+		if util.HashBytes(result) != file.TargetSHA256 {
+			s.logger.Error("Patched file does not match its expected hash: " + file.Path)
+			return fmt.Errorf("%s does not match its expected hash after patching", file.Path)
+		}
+		results[file.Path] = result
+	}
+
+	for _, file := range manifest.Files {
+		dstPath := filepath.Join(gameDir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dstPath, results[file.Path], 0644); err != nil {
+			s.logger.Error("Failed to write file: " + file.Path)
+			return err
+		}
+	}
+
+	s.logger.Success(fmt.Sprintf("Applied delta patch to %d files", len(manifest.Files)))
+	return nil
+}
+
+// readPatchSummary reads the patch summary of a patched game
+func (s *ExportService) readPatchSummary(gameDir string) (*domain.PatchSummary, error) {
+	summaryPath := filepath.Join(gameDir, "patch-summary.json")
+	summaryData, err := os.ReadFile(summaryPath)
+	if err != nil {
+		s.logger.Error("Failed to read patch-summary.json - game may not be patched")
+		return nil, errors.New("patch-summary.json not found - game may not be patched")
+	}
+
+	var patchSummary domain.PatchSummary
+	if err := json.Unmarshal(summaryData, &patchSummary); err != nil {
+		s.logger.Error("Failed to parse patch-summary.json")
+		return nil, err
+	}
+
+	if len(patchSummary.PatchedFiles) == 0 {
+		s.logger.Error("No patched files found in patch summary")
+		return nil, errors.New("no patched files found")
+	}
+	return &patchSummary, nil
+}
+
 // sanitizeFilename removes or replaces characters that are invalid in filenames
 func sanitizeFilename(name string) string {
 	// Characters not allowed in Windows filenames
@@ -10,6 +10,7 @@ import (
 	"htpatcher/internal/util"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -101,9 +102,27 @@ func (s *GameService) GetGameInfoFromExePath(filePath string) (*domain.GameInfo,
 	gameInfo.GameTitle = systemInfo.GameTitle
 	s.logger.Info(fmt.Sprintf("Game title: \"%s\"", gameInfo.GameTitle))
 
+	gameInfo.Engine = detectEngine(gameInfo.JsPath, &systemInfo)
+	s.logger.Info(fmt.Sprintf("Engine: RPG Maker %s", strings.ToUpper(gameInfo.Engine)))
+
 	return &gameInfo, nil
 }
 
+// detectEngine tells whether a game was made with MV or MZ from its core script,
+// or from the settings only MZ has in System.json when the script was renamed
+func detectEngine(jsPath string, system *rpgmaker.System) string {
+	if _, err := os.Stat(filepath.Join(jsPath, "rmmz_core.js")); err == nil {
+		return domain.EngineMZ
+	}
+	if _, err := os.Stat(filepath.Join(jsPath, "rpg_core.js")); err == nil {
+		return domain.EngineMV
+	}
+	if system.Advanced != nil {
+		return domain.EngineMZ
+	}
+	return domain.EngineMV
+}
+
 // LaunchGame launches a game executable
 func (s *GameService) LaunchGame(exePath string) error {
 	workingDir := filepath.Dir(exePath)
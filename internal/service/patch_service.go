@@ -8,29 +8,51 @@ import (
 	"fmt"
 	"htpatcher/internal/domain"
 	"htpatcher/internal/domain/rpgmaker"
+	"htpatcher/internal/lint"
 	"htpatcher/internal/patcher"
 	"htpatcher/internal/util"
 	"io"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// maxLoggedIssues is the number of dictionary issues logged before the rest are only counted
+const maxLoggedIssues = 20
+
 // PatchRepositoryInterface interface with all patch operations
 type PatchRepositoryInterface interface {
 	Open(path string) (*zip.ReadCloser, error)
 	ReadDictionary(zipReader *zip.ReadCloser) (map[string]string, error)
+	ReadGlossary(zipReader *zip.ReadCloser) (map[string]string, error)
 	ReadConfig(zipReader *zip.ReadCloser) (*domain.Config, error)
 	GetAllOverrides(zipReader *zip.ReadCloser) ([]string, error)
+	GetOverrideSizes(zipReader *zip.ReadCloser) (map[string]int64, error)
 	ReadFileFromZip(zipReader *zip.ReadCloser, path string) ([]byte, error)
 }
 
+// PatchVerifier checks the signature of patches before they are loaded
+type PatchVerifier interface {
+	VerifyPatch(patchPath string) (*domain.PatchSignature, error)
+	CheckPatch(patchPath string) (*domain.PatchSignature, error)
+}
+
+// OverrideSettingsProvider provides the folders patches may write override files to
+type OverrideSettingsProvider interface {
+	GetOverrideSettings() domain.OverrideSettings
+}
+
 // PatchService handles patch operations
 type PatchService struct {
 	patchRepo      PatchRepositoryInterface
+	verifier       PatchVerifier
+	overrides      OverrideSettingsProvider
 	patcherEngine  *patcher.Engine
 	pluginPatcher  *patcher.PluginPatcher
 	creditsPatcher *patcher.CreditsPatcher
@@ -38,9 +60,11 @@ type PatchService struct {
 }
 
 // NewPatchService creates a new patch service
-func NewPatchService(patchRepo PatchRepositoryInterface, logger Logger) *PatchService {
+func NewPatchService(patchRepo PatchRepositoryInterface, verifier PatchVerifier, overrides OverrideSettingsProvider, logger Logger) *PatchService {
 	return &PatchService{
 		patchRepo:      patchRepo,
+		verifier:       verifier,
+		overrides:      overrides,
 		patcherEngine:  patcher.NewEngine(logger),
 		pluginPatcher:  patcher.NewPluginPatcher(logger),
 		creditsPatcher: patcher.NewCreditsPatcher(),
@@ -66,12 +90,40 @@ func (s *PatchService) SelectPatchFile(ctx context.Context) (*domain.PatchInfo,
 	return s.LoadPatchInfo(filePath)
 }
 
-// LoadPatchInfo loads patch information from a file
+// LoadPatchInfo loads patch information from a file and lints its dictionary
 func (s *PatchService) LoadPatchInfo(filePath string) (*domain.PatchInfo, error) {
+	patchInfo, err := s.readPatchInfo(filePath)
+	if err != nil {
+		return nil, err
+	}
+	patchInfo.Issues = s.CheckDictionary(patchInfo, nil, nil)
+	return patchInfo, nil
+}
+
+// ReloadPatchInfo reads a patch again from its file, verifying it, and replaces the given patch info with its content.
+// Patch info that went through the frontend is reloaded before being applied so that only the verified patch is.
+func (s *PatchService) ReloadPatchInfo(patchInfo *domain.PatchInfo) error {
+	verified, err := s.readPatchInfo(patchInfo.PatchPath)
+	if err != nil {
+		return err
+	}
+	*patchInfo = *verified
+	return nil
+}
+
+// readPatchInfo verifies a patch and reads its content
+func (s *PatchService) readPatchInfo(filePath string) (*domain.PatchInfo, error) {
 	patchInfo := &domain.PatchInfo{
 		PatchPath: filePath,
 	}
 
+	// Verify the signature before reading anything else
+	signature, err := s.verifier.CheckPatch(filePath)
+	if err != nil {
+		return nil, err
+	}
+	patchInfo.Signature = signature
+
 	// Open the patch file
 	r, err := s.patchRepo.Open(patchInfo.PatchPath)
 	if err != nil {
@@ -97,15 +149,290 @@ func (s *PatchService) LoadPatchInfo(filePath string) (*domain.PatchInfo, error)
 		return nil, err
 	}
 
+	// Read glossary
+	patchInfo.Glossary, err = s.patchRepo.ReadGlossary(r)
+	if err != nil {
+		s.logger.Error("Failed to read glossary.json")
+		return nil, err
+	}
+
 	// Get overrides
 	patchInfo.Overrides, err = s.patchRepo.GetAllOverrides(r)
 	if err != nil {
 		return nil, err
 	}
-
+	if err := s.validateOverrides(patchInfo.Overrides); err != nil {
+		return nil, err
+	}
+	if err := s.validatePluginsToPatch(patchInfo.Config); err != nil {
+		return nil, err
+	}
+	if err := s.validateDataFilesToPatch(patchInfo.Config); err != nil {
+		return nil, err
+	}
 	return patchInfo, nil
 }
 
+// CheckDictionary lints the dictionary of a patch, logging a warning for each issue found.
+// It checks escape codes and the glossary terms, and with the source texts of a game the length of translations.
+// The game is nil when the patch is not applied to one yet.
+func (s *PatchService) CheckDictionary(patchInfo *domain.PatchInfo, gameInfo *domain.GameInfo, sources []domain.SourceText) []domain.DictionaryIssue {
+	issues := lintDictionary(patchInfo.Dictionary, patchInfo.Glossary, patchInfo.Config, gameInfo, sources)
+	for i, issue := range issues {
+		if i == maxLoggedIssues {
+			s.logger.Warn(fmt.Sprintf("...and %d more", len(issues)-maxLoggedIssues))
+			break
+		}
+		s.logger.Warn(fmt.Sprintf("%s: %s", util.NoNewline(issue.Translation), issue.Message))
+	}
+	if len(issues) > 0 {
+		s.logger.Warn(fmt.Sprintf("Found %d issues in the dictionary", len(issues)))
+	}
+	return issues
+}
+
+// validateOverrides makes sure every override path stays inside the game folder and the allowed subtrees
+func (s *PatchService) validateOverrides(overrides []string) error {
+	allowedPaths := s.overrides.GetOverrideSettings().AllowedPaths
+	for _, override := range overrides {
+		if err := util.ValidateRelativePath(override); err != nil {
+			s.logger.Error("Refusing override: " + err.Error())
+			return fmt.Errorf("unsafe override path: %w", err)
+		}
+		if len(allowedPaths) > 0 && !util.IsInSubtree(override, allowedPaths) {
+			s.logger.Error(fmt.Sprintf("Refusing override %s: patches may only write to %s", override, strings.Join(allowedPaths, ", ")))
+			return fmt.Errorf("override %s is outside the allowed folders", override)
+		}
+	}
+	return nil
+}
+
+// validateDataFilesToPatch makes sure the optional data files a patch opts in to exist,
+// and that the data fields it adds are valid
+func (s *PatchService) validateDataFilesToPatch(config *domain.Config) error {
+	for _, file := range config.DataFilesToPatch {
+		if !slices.Contains(patcher.OptionalDataFiles, file) {
+			s.logger.Error("Unknown data file to patch: " + file)
+			return fmt.Errorf("unknown data file to patch %s, expected one of %s", file, strings.Join(patcher.OptionalDataFiles, ", "))
+		}
+	}
+	for _, file := range config.DataFieldsToPatch {
+		if err := patcher.CheckDataFile(file); err != nil {
+			s.logger.Error(fmt.Sprintf("Invalid data fields of %s: %s", file.File, err.Error()))
+			return fmt.Errorf("invalid data fields of %s: %w", file.File, err)
+		}
+	}
+	return nil
+}
+
+// validatePluginsToPatch makes sure every replace rule and parameter path of the plugins and plugin commands is valid
+func (s *PatchService) validatePluginsToPatch(config *domain.Config) error {
+	for _, parameter := range config.ParametersToPatch {
+		if (parameter.RootType == "array" || parameter.RootType == "object") && len(parameter.ParameterPathsToPatch) == 0 && !parameter.Recursive {
+			s.logger.Warn(fmt.Sprintf("Plugin command %s.%s has an %s root but no parameter paths, nothing of it is translated unless recursive is set", parameter.Plugin, parameter.Function, parameter.RootType))
+		}
+		for _, path := range parameter.ParameterPathsToPatch {
+			if err := patcher.CheckParameterPathToPatch(path); err != nil {
+				s.logger.Error(fmt.Sprintf("Invalid configuration of plugin command %s.%s: %s", parameter.Plugin, parameter.Function, err.Error()))
+				return fmt.Errorf("invalid configuration of plugin command %s.%s: %w", parameter.Plugin, parameter.Function, err)
+			}
+		}
+	}
+	for _, pluginToPatch := range config.PluginsToPatch {
+		errs := pluginConfigErrors(pluginToPatch)
+		for _, message := range errs {
+			s.logger.Error(fmt.Sprintf("Invalid configuration of plugin %s: %s", pluginToPatch.Plugin, message))
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("invalid configuration of plugin %s: %s", pluginToPatch.Plugin, errs[0])
+		}
+	}
+	return nil
+}
+
+// pluginConfigErrors describes the invalid replace rules and parameter paths of a plugin
+func pluginConfigErrors(pluginToPatch domain.PluginToPatch) []string {
+	var errs []string
+	for i, rule := range pluginToPatch.ReplaceRules {
+		if err := patcher.CheckReplaceRule(rule); err != nil {
+			errs = append(errs, fmt.Sprintf("Replace rule #%d: %s", i+1, err.Error()))
+		}
+	}
+	for _, path := range pluginToPatch.ParameterPaths {
+		if err := patcher.CheckParameterPathToPatch(path); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	return errs
+}
+
+// CheckOverrides makes sure the overrides of a patch can be written to a game without leaving its folder,
+// including through symlinks inside the game folder
+func (s *PatchService) CheckOverrides(gameInfo *domain.GameInfo, patchInfo *domain.PatchInfo) error {
+	if err := s.validateOverrides(patchInfo.Overrides); err != nil {
+		return err
+	}
+	for _, override := range patchInfo.Overrides {
+		if _, err := util.SafeJoin(gameInfo.GameDir, override); err != nil {
+			s.logger.Error("Refusing override: " + err.Error())
+			return fmt.Errorf("unsafe override path: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkEngine makes sure a patch was made for the engine of a game, and warns about settings the engine ignores
+func (s *PatchService) checkEngine(gameInfo *domain.GameInfo, config *domain.Config) error {
+	if config.Engine != "" && gameInfo.Engine != "" && config.Engine != gameInfo.Engine {
+		s.logger.Error(fmt.Sprintf("This patch is made for RPG Maker %s, the game uses RPG Maker %s", strings.ToUpper(config.Engine), strings.ToUpper(gameInfo.Engine)))
+		return fmt.Errorf("patch made for %s, game made with %s", config.Engine, gameInfo.Engine)
+	}
+	if config.Fonts != nil && gameInfo.Engine == domain.EngineMV {
+		s.logger.Warn("The font settings of the patch only apply to RPG Maker MZ games, override css/game.css instead")
+	}
+	return nil
+}
+
+// collectDataSourceTexts collects the source texts of the data files of a game, skipping the ones that cannot be read
+func (s *PatchService) collectDataSourceTexts(jsonFiles []string, config *domain.Config) []domain.SourceText {
+	sources := []domain.SourceText{}
+	for _, jsonFile := range jsonFiles {
+		data, err := os.ReadFile(jsonFile)
+		if err != nil {
+			continue
+		}
+		texts, err := patcher.CollectSourceTexts(jsonFile, data, config)
+		if err != nil {
+			continue // Reported when the file is patched
+		}
+		sources = append(sources, texts...)
+	}
+	return sources
+}
+
+// lintDictionary runs every dictionary linter. Escape codes are checked for the engine detected in the game
+// when there is one, the engine the patch declares otherwise. Lengths are only checked for the given source texts,
+// which tell the windows showing them.
+func lintDictionary(dictionary map[string]string, glossary map[string]string, config *domain.Config, gameInfo *domain.GameInfo, sources []domain.SourceText) []domain.DictionaryIssue {
+	engine := config.Engine
+	if gameInfo != nil && gameInfo.Engine != "" {
+		engine = gameInfo.Engine
+	}
+	issues := lint.CheckEscapeCodes(dictionary, engine)
+	issues = append(issues, lint.CheckLength(dictionary, sources, config)...)
+	return append(issues, lint.CheckGlossary(dictionary, glossary)...)
+}
+
+// InspectPatch describes the contents of a patch and what it will change, without applying anything.
+// Unlike LoadPatchInfo, it also inspects patches made for a newer version of the patcher.
+func (s *PatchService) InspectPatch(filePath string) (*domain.PatchInspection, error) {
+	r, err := s.patchRepo.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	inspection := &domain.PatchInspection{
+		PatchPath:       filePath,
+		UnsafeOverrides: []string{},
+		Plugins:         []domain.PluginInspection{},
+	}
+	inspection.Signature, err = s.verifier.VerifyPatch(filePath)
+	if err != nil {
+		return nil, err
+	}
+	inspection.Config, err = s.patchRepo.ReadConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	inspection.SupportedVersion = inspection.Config.Version <= Version
+
+	dictionary, err := s.patchRepo.ReadDictionary(r)
+	if err != nil {
+		return nil, err
+	}
+	glossary, err := s.patchRepo.ReadGlossary(r)
+	if err != nil {
+		return nil, err
+	}
+	inspection.DictionarySize = len(dictionary)
+	inspection.GlossarySize = len(glossary)
+	inspection.Issues = lintDictionary(dictionary, glossary, inspection.Config, nil, nil)
+
+	sizes, err := s.patchRepo.GetOverrideSizes(r)
+	if err != nil {
+		return nil, err
+	}
+	inspection.Overrides = buildOverrideTree(sizes)
+	allowedPaths := s.overrides.GetOverrideSettings().AllowedPaths
+	for _, override := range slices.Sorted(maps.Keys(sizes)) {
+		if err := util.ValidateRelativePath(override); err != nil {
+			inspection.UnsafeOverrides = append(inspection.UnsafeOverrides, err.Error())
+		} else if len(allowedPaths) > 0 && !util.IsInSubtree(override, allowedPaths) {
+			inspection.UnsafeOverrides = append(inspection.UnsafeOverrides, override+" is outside the allowed folders")
+		}
+	}
+
+	for _, pluginToPatch := range inspection.Config.PluginsToPatch {
+		plugin := domain.PluginInspection{
+			Plugin:             pluginToPatch.Plugin,
+			HasScript:          pluginToPatch.ParametersPatchScript != "",
+			ReplaceRuleCount:   len(pluginToPatch.ReplaceRules),
+			ParameterPathCount: len(pluginToPatch.ParameterPaths),
+		}
+		if plugin.HasScript {
+			if err := patcher.CheckPatchScript(pluginToPatch.Plugin, pluginToPatch.ParametersPatchScript); err != nil {
+				plugin.ScriptError = strings.TrimSpace(err.Error())
+			}
+		}
+		plugin.ConfigErrors = pluginConfigErrors(pluginToPatch)
+		inspection.Plugins = append(inspection.Plugins, plugin)
+	}
+
+	return inspection, nil
+}
+
+// buildOverrideTree builds the folder tree of the override files, folders first and sorted by name
+func buildOverrideTree(sizes map[string]int64) domain.OverrideNode {
+	root := domain.OverrideNode{IsDir: true}
+	for path, size := range sizes {
+		node := &root
+		parts := strings.Split(path, "/")
+		for i, part := range parts {
+			node.Size += size
+			index := slices.IndexFunc(node.Children, func(child domain.OverrideNode) bool { return child.Name == part })
+			if index == -1 {
+				node.Children = append(node.Children, domain.OverrideNode{
+					Name:  part,
+					Path:  strings.Join(parts[:i+1], "/"),
+					IsDir: i < len(parts)-1,
+				})
+				index = len(node.Children) - 1
+			}
+			node = &node.Children[index]
+		}
+		node.Size = size
+	}
+	sortOverrideTree(&root)
+	return root
+}
+
+// sortOverrideTree sorts the children of a node recursively, folders first
+func sortOverrideTree(node *domain.OverrideNode) {
+	slices.SortFunc(node.Children, func(a, b domain.OverrideNode) int {
+		if a.IsDir != b.IsDir {
+			if a.IsDir {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	for i := range node.Children {
+		sortOverrideTree(&node.Children[i])
+	}
+}
+
 // FetchAllPatches fetches all available patches from the API
 func (s *PatchService) FetchAllPatches() ([]domain.PatchEntry, error) {
 	response, err := http.Get("https://htranslations.com/api/patches")
@@ -130,6 +457,19 @@ func (s *PatchService) FetchAllPatches() ([]domain.PatchEntry, error) {
 func (s *PatchService) ApplyPatch(ctx context.Context, gameInfo *domain.GameInfo, patchInfo *domain.PatchInfo) error {
 	s.logger.Info("Starting patch application...")
 
+	// Apply what the patch file holds, verified again, rather than what was handed over
+	if err := s.ReloadPatchInfo(patchInfo); err != nil {
+		return err
+	}
+
+	// Check every override before anything is written
+	if err := s.CheckOverrides(gameInfo, patchInfo); err != nil {
+		return err
+	}
+	if err := s.checkEngine(gameInfo, patchInfo.Config); err != nil {
+		return err
+	}
+
 	// Track all patched files (relative paths from game directory)
 	var patchedFiles []string
 
@@ -142,9 +482,20 @@ func (s *PatchService) ApplyPatch(ctx context.Context, gameInfo *domain.GameInfo
 	}
 	s.logger.Info(fmt.Sprintf("Found %d JSON files to patch", len(jsonFiles)))
 
+	// Now that the game is known, check the escape codes for its engine and the lengths against its windows
+	patchInfo.Issues = s.CheckDictionary(patchInfo, gameInfo, s.collectDataSourceTexts(jsonFiles, patchInfo.Config))
+
+	// Load the command hook of the patch, if any
+	commandHook, err := s.patcherEngine.NewCommandHook(ctx, patchInfo)
+	if err != nil {
+		s.logger.Error("Failed to load the commands patch script: " + err.Error())
+		return err
+	}
+	defer commandHook.Close()
+
 	// Patch all data files
 	for _, jsonFile := range jsonFiles {
-		err = s.patcherEngine.PatchDataFile(ctx, jsonFile, patchInfo)
+		err = s.patcherEngine.PatchDataFile(ctx, jsonFile, gameInfo.Engine, patchInfo, commandHook)
 		if err != nil {
 			s.logger.Error("Error patching file: " + filepath.Base(jsonFile))
 			return err
@@ -156,7 +507,7 @@ func (s *PatchService) ApplyPatch(ctx context.Context, gameInfo *domain.GameInfo
 
 	// Patch plugins.js
 	pluginsJsPath := filepath.Join(gameInfo.JsPath, "plugins.js")
-	err = s.pluginPatcher.UpdatePluginsJs(ctx, pluginsJsPath, patchInfo.Config.PluginsToPatch, patchInfo.Dictionary)
+	err = s.pluginPatcher.UpdatePluginsJs(ctx, pluginsJsPath, patchInfo.Config.PluginsToPatch, patchInfo.Dictionary, patchInfo.Config.WrapWidth)
 	if err != nil {
 		s.logger.Error("Failed to update plugins.js")
 		return err
@@ -168,7 +519,7 @@ func (s *PatchService) ApplyPatch(ctx context.Context, gameInfo *domain.GameInfo
 		patchedFiles = append(patchedFiles, relPath)
 	}
 
-	// Apply replace rules
+	// Apply replace rules and translate plugin strings
 	for _, pluginToPatch := range patchInfo.Config.PluginsToPatch {
 		for i, replaceRule := range pluginToPatch.ReplaceRules {
 			err = s.pluginPatcher.ApplyReplaceRule(ctx, gameInfo.JsPath, pluginToPatch.Plugin, replaceRule, i+1)
@@ -176,18 +527,19 @@ func (s *PatchService) ApplyPatch(ctx context.Context, gameInfo *domain.GameInfo
 				s.logger.Error("Failed to apply plugin replace rule")
 				return err
 			}
-			// Track patched plugin file
-			pluginJsPath := filepath.Join(gameInfo.JsPath, "plugins", pluginToPatch.Plugin+".js")
-			relPath, _ := filepath.Rel(gameInfo.GameDir, pluginJsPath)
-			// Only add if not already in the list
-			found := false
-			for _, f := range patchedFiles {
-				if f == relPath {
-					found = true
-					break
-				}
+		}
+		// Literals are translated after the replace rules, which match the original source
+		if pluginToPatch.TranslateStrings {
+			err = s.pluginPatcher.TranslatePluginStrings(ctx, gameInfo.JsPath, pluginToPatch.Plugin, patchInfo.Dictionary)
+			if err != nil {
+				s.logger.Error("Failed to translate plugin strings")
+				return err
 			}
-			if !found {
+		}
+		// Track patched plugin files
+		for _, target := range patcher.PluginFileTargets(gameInfo.JsPath, pluginToPatch) {
+			relPath, _ := filepath.Rel(gameInfo.GameDir, target)
+			if !slices.Contains(patchedFiles, relPath) {
 				patchedFiles = append(patchedFiles, relPath)
 			}
 		}
@@ -207,7 +559,16 @@ func (s *PatchService) ApplyPatch(ctx context.Context, gameInfo *domain.GameInfo
 				s.logger.Error("Failed to read override")
 				return err
 			}
-			err = os.WriteFile(filepath.Join(gameInfo.GameDir, override), data, 0644)
+			overridePath, err := util.SafeJoin(gameInfo.GameDir, override)
+			if err != nil {
+				s.logger.Error("Refusing override: " + err.Error())
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(overridePath), 0755); err != nil {
+				s.logger.Error("Failed to create override directory")
+				return err
+			}
+			err = os.WriteFile(overridePath, data, 0644)
 			if err != nil {
 				s.logger.Error("Failed to write override")
 				return err
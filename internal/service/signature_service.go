@@ -0,0 +1,295 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"htpatcher/internal/domain"
+	"htpatcher/internal/util"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// embeddedTrustedKeys lists the publisher keys shipped with the app
+//
+//go:embed trusted_keys.json
+var embeddedTrustedKeys []byte
+
+// signatureManifestVersion is the version of the manifests written when signing
+const signatureManifestVersion = 1
+
+// SignatureRepository reads and writes patch signatures
+type SignatureRepository interface {
+	Open(path string) (*zip.ReadCloser, error)
+	HashFiles(zipReader *zip.ReadCloser) (map[string]string, error)
+	ReadSignature(zipReader *zip.ReadCloser) ([]byte, []byte, error)
+	WriteFilesToZip(path string, files map[string][]byte) error
+}
+
+// SignatureSettingsProvider provides the signature policy and the keys added by the user
+type SignatureSettingsProvider interface {
+	GetSignatureSettings() domain.SignatureSettings
+}
+
+// SignatureService signs patches and verifies them against trusted publisher keys
+type SignatureService struct {
+	patchRepo SignatureRepository
+	settings  SignatureSettingsProvider
+	logger    Logger
+}
+
+// NewSignatureService creates a new signature service
+func NewSignatureService(patchRepo SignatureRepository, settings SignatureSettingsProvider, logger Logger) *SignatureService {
+	return &SignatureService{
+		patchRepo: patchRepo,
+		settings:  settings,
+		logger:    logger,
+	}
+}
+
+// GetTrustedKeys returns the keys shipped with the app followed by the keys added by the user
+func (s *SignatureService) GetTrustedKeys() []domain.TrustedKey {
+	return append(embeddedKeys(), s.settings.GetSignatureSettings().TrustedKeys...)
+}
+
+// embeddedKeys returns the publisher keys shipped with the app
+func embeddedKeys() []domain.TrustedKey {
+	keys := []domain.TrustedKey{}
+	if err := json.Unmarshal(embeddedTrustedKeys, &keys); err != nil {
+		return []domain.TrustedKey{}
+	}
+	for i := range keys {
+		keys[i].Embedded = true
+	}
+	return keys
+}
+
+// refusalHint completes the refusal of a patch when the app ships without publisher keys,
+// since only the keys added by the user can then be trusted
+func refusalHint() string {
+	if len(embeddedKeys()) > 0 {
+		return ""
+	}
+	return " (no publisher keys are built into this version, add the publisher's key in the settings or allow unsigned patches)"
+}
+
+// VerifyPatch checks the signature of a patch and the hashes of its files.
+// A tampered patch is reported with the invalid status, errors are only returned when the patch cannot be read.
+func (s *SignatureService) VerifyPatch(patchPath string) (*domain.PatchSignature, error) {
+	r, err := s.patchRepo.Open(patchPath)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	manifestData, signature, err := s.patchRepo.ReadSignature(r)
+	if err != nil {
+		return nil, err
+	}
+	if manifestData == nil && signature == nil {
+		return &domain.PatchSignature{Status: domain.SignatureStatusUnsigned}, nil
+	}
+	invalid := func(reason string) (*domain.PatchSignature, error) {
+		return &domain.PatchSignature{Status: domain.SignatureStatusInvalid, Error: reason}, nil
+	}
+	if manifestData == nil || signature == nil {
+		return invalid("the signature is incomplete")
+	}
+
+	var manifest domain.PatchManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return invalid("the manifest cannot be read")
+	}
+	if !util.VerifySignature(manifest.PublicKey, manifestData, strings.TrimSpace(string(signature))) {
+		return invalid("the manifest does not match its signature")
+	}
+
+	hashes, err := s.patchRepo.HashFiles(r)
+	if err != nil {
+		return invalid(err.Error())
+	}
+	for _, name := range slices.Sorted(maps.Keys(hashes)) {
+		expected, ok := manifest.Files[name]
+		if !ok {
+			return invalid(name + " was added after signing")
+		}
+		if hashes[name] != expected {
+			return invalid(name + " was modified after signing")
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(manifest.Files)) {
+		if _, ok := hashes[name]; !ok {
+			return invalid(name + " was removed after signing")
+		}
+	}
+
+	result := &domain.PatchSignature{
+		Status:      domain.SignatureStatusUntrusted,
+		Publisher:   manifest.Publisher,
+		Fingerprint: util.KeyFingerprint(manifest.PublicKey),
+	}
+	trusted := s.GetTrustedKeys()
+	if index := slices.IndexFunc(trusted, func(key domain.TrustedKey) bool { return key.PublicKey == manifest.PublicKey }); index != -1 {
+		result.Status = domain.SignatureStatusTrusted
+		result.Publisher = trusted[index].Name
+	}
+	return result, nil
+}
+
+// CheckPatch verifies a patch and enforces the signature policy.
+// Tampered patches are always refused, unsigned and untrusted ones only when a trusted signature is required.
+func (s *SignatureService) CheckPatch(patchPath string) (*domain.PatchSignature, error) {
+	signature, err := s.VerifyPatch(patchPath)
+	if err != nil {
+		s.logger.Error("Failed to verify the patch signature")
+		return nil, err
+	}
+
+	required := s.settings.GetSignatureSettings().Policy == domain.SignaturePolicyRequire
+	switch signature.Status {
+	case domain.SignatureStatusTrusted:
+		s.logger.Info("Patch signed by " + signature.Publisher)
+	case domain.SignatureStatusInvalid:
+		s.logger.Error("The patch was tampered with: " + signature.Error)
+		return nil, errors.New("patch signature is invalid")
+	case domain.SignatureStatusUntrusted:
+		message := fmt.Sprintf("Patch signed by %s with an untrusted key (%s)", signature.Publisher, signature.Fingerprint)
+		if required {
+			s.logger.Error(message + refusalHint())
+			return nil, errors.New("patch is not signed by a trusted key" + refusalHint())
+		}
+		s.logger.Warn(message)
+	default:
+		if required {
+			s.logger.Error("Patch is not signed, only signed patches are allowed" + refusalHint())
+			return nil, errors.New("patch is not signed" + refusalHint())
+		}
+		s.logger.Warn("Patch is not signed, only apply it if you trust where it comes from")
+	}
+	return signature, nil
+}
+
+// GenerateSigningKey generates a key pair for a publisher and saves it to a key file.
+// The returned key holds only the public part, to be shared with the users of the patches.
+func (s *SignatureService) GenerateSigningKey(ctx context.Context, publisher string) (*domain.TrustedKey, error) {
+	publisher = strings.TrimSpace(publisher)
+	if publisher == "" {
+		return nil, errors.New("publisher name is required")
+	}
+
+	keyPath, err := runtime.SaveFileDialog(ctx, runtime.SaveDialogOptions{
+		Title:           "Save the Signing Key",
+		DefaultFilename: publisher + ".htkey",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "Signing key",
+				Pattern:     "*.htkey",
+			},
+		},
+	})
+	if err != nil || keyPath == "" {
+		return nil, err
+	}
+
+	publicKey, privateKey, err := util.GenerateSigningKey()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(domain.SigningKey{
+		Publisher:  publisher,
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyPath, data, 0600); err != nil {
+		s.logger.Error("Failed to write " + filepath.Base(keyPath))
+		return nil, err
+	}
+
+	s.logger.Success("Saved the signing key of " + publisher + ", keep it private")
+	return &domain.TrustedKey{Name: publisher, PublicKey: publicKey}, nil
+}
+
+// SignPatch signs every file of a patch with a key file, replacing any previous signature
+func (s *SignatureService) SignPatch(ctx context.Context) error {
+	keyPath, err := runtime.OpenFileDialog(ctx, runtime.OpenDialogOptions{
+		Title: "Select the Signing Key",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "Signing key",
+				Pattern:     "*.htkey",
+			},
+		},
+	})
+	if err != nil || keyPath == "" {
+		return err
+	}
+	keyData, err := os.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+	var key domain.SigningKey
+	if err := json.Unmarshal(keyData, &key); err != nil || key.PrivateKey == "" {
+		s.logger.Error(filepath.Base(keyPath) + " is not a signing key")
+		return errors.New("invalid signing key")
+	}
+
+	patchPath, err := selectPatchPath(ctx, "Select the Patch to sign")
+	if err != nil || patchPath == "" {
+		return err
+	}
+
+	r, err := s.patchRepo.Open(patchPath)
+	if err != nil {
+		s.logger.Error("Failed to open patch")
+		return err
+	}
+	hashes, err := s.patchRepo.HashFiles(r)
+	r.Close()
+	if err != nil {
+		s.logger.Error("Failed to hash the patch files")
+		return err
+	}
+
+	manifest, err := json.MarshalIndent(domain.PatchManifest{
+		Version:   signatureManifestVersion,
+		Publisher: key.Publisher,
+		PublicKey: key.PublicKey,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Files:     hashes,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	signature, err := util.Sign(key.PrivateKey, manifest)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return err
+	}
+	if !util.VerifySignature(key.PublicKey, manifest, signature) {
+		s.logger.Error("The public and private keys of " + filepath.Base(keyPath) + " do not match")
+		return errors.New("signing key does not match")
+	}
+
+	if err := s.patchRepo.WriteFilesToZip(patchPath, map[string][]byte{
+		"manifest.json": manifest,
+		"manifest.sig":  []byte(signature),
+	}); err != nil {
+		s.logger.Error("Failed to write the signature")
+		return err
+	}
+
+	s.logger.Success(fmt.Sprintf("Signed %d files of %s as %s", len(hashes), filepath.Base(patchPath), key.Publisher))
+	return nil
+}
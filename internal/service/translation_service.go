@@ -0,0 +1,455 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"htpatcher/internal/domain"
+	"htpatcher/internal/interchange"
+	"htpatcher/internal/patcher"
+	"htpatcher/internal/translator"
+	"htpatcher/internal/util"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// machineTranslatedName is the patch file listing the dictionary keys that were machine translated
+const machineTranslatedName = "machine-translated.json"
+
+// TranslationRepository reads and writes patch dictionaries
+type TranslationRepository interface {
+	Open(path string) (*zip.ReadCloser, error)
+	ReadConfig(zipReader *zip.ReadCloser) (*domain.Config, error)
+	ReadFileFromZip(zipReader *zip.ReadCloser, path string) ([]byte, error)
+	WriteFileToZip(path string, name string, data []byte) error
+}
+
+// TranslationService converts patch dictionaries to and from translation interchange formats
+type TranslationService struct {
+	patchRepo TranslationRepository
+	logger    Logger
+}
+
+// NewTranslationService creates a new translation service
+func NewTranslationService(patchRepo TranslationRepository, logger Logger) *TranslationService {
+	return &TranslationService{patchRepo: patchRepo, logger: logger}
+}
+
+// ExportTranslations exports the dictionary of a patch along with the source texts of the game.
+// readOriginals provides the original data files from the backup so a patched game still yields its source texts.
+func (s *TranslationService) ExportTranslations(ctx context.Context, gameInfo *domain.GameInfo, format string, readOriginals func(fn func(relPath string, r io.Reader) error) error) error {
+	patchPath, err := selectPatchPath(ctx, "Select the Patch to export")
+	if err != nil || patchPath == "" {
+		return err
+	}
+
+	patch, err := s.readPatchDictionary(patchPath)
+	if err != nil {
+		return err
+	}
+	config := patch.config
+	dictionary, err := interchange.ReadDictionary(patch.original)
+	if err != nil {
+		s.logger.Error("Failed to parse dictionary.json")
+		return err
+	}
+
+	sources, err := s.collectSourceTexts(gameInfo, config, readOriginals)
+	if err != nil {
+		return err
+	}
+	units := interchange.BuildUnits(dictionary, sources)
+	machineTranslated := patch.machineTranslatedKeys()
+	for i := range units {
+		units[i].MachineTranslated = machineTranslated[units[i].Key]
+	}
+
+	patchName := strings.TrimSuffix(filepath.Base(patchPath), filepath.Ext(patchPath))
+	outputPath, err := runtime.SaveFileDialog(ctx, runtime.SaveDialogOptions{
+		Title:           "Export Translations",
+		DefaultFilename: patchName + interchange.Extension(format),
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "Translation file",
+				Pattern:     "*" + interchange.Extension(format),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// User cancelled
+	if outputPath == "" {
+		return nil
+	}
+
+	targetLanguage := config.Locale
+	if targetLanguage == "" {
+		targetLanguage = "en"
+	}
+	var buf bytes.Buffer
+	err = interchange.Export(&buf, format, units, interchange.Metadata{
+		Original:       filepath.Base(patchPath),
+		SourceLanguage: "ja",
+		TargetLanguage: targetLanguage,
+	})
+	if err != nil {
+		s.logger.Error("Failed to export translations")
+		return err
+	}
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		s.logger.Error("Failed to write " + filepath.Base(outputPath))
+		return err
+	}
+
+	s.logger.Success(fmt.Sprintf("Exported %d translations and %d untranslated texts", len(dictionary.Keys), len(units)-len(dictionary.Keys)))
+	return nil
+}
+
+// ImportTranslations replaces the dictionary of a patch with the translations of an interchange file.
+// dictionary.json is left byte for byte unchanged when the translations are the same.
+// Translations still flagged for review are kept in the list of machine translated keys.
+func (s *TranslationService) ImportTranslations(ctx context.Context) error {
+	inputPath, err := runtime.OpenFileDialog(ctx, runtime.OpenDialogOptions{
+		Title: "Select the Translation file",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "Translation file",
+				Pattern:     "*.po;*.xlf;*.xliff;*.csv;*.tsv",
+			},
+		},
+	})
+	if err != nil || inputPath == "" {
+		return err
+	}
+
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+	format, err := interchange.DetectFormat(inputPath, data)
+	if err != nil {
+		s.logger.Error(err.Error())
+		return err
+	}
+	units, err := interchange.Import(bytes.NewReader(data), format)
+	if err != nil {
+		s.logger.Error("Failed to read " + filepath.Base(inputPath))
+		return err
+	}
+	if !slices.ContainsFunc(units, func(unit domain.TranslationUnit) bool { return unit.Target != "" }) {
+		s.logger.Error("No translations found in " + filepath.Base(inputPath))
+		return errors.New("no translations found")
+	}
+
+	patchPath, err := selectPatchPath(ctx, "Select the Patch to import into")
+	if err != nil || patchPath == "" {
+		return err
+	}
+
+	patch, err := s.readPatchDictionary(patchPath)
+	if err != nil {
+		return err
+	}
+	original, err := interchange.ReadDictionary(patch.original)
+	if err != nil {
+		s.logger.Error("Failed to parse dictionary.json")
+		return err
+	}
+	dictionary := interchange.BuildDictionary(units, original)
+
+	// Translations still flagged for review stay marked as machine translated
+	machineTranslated := make(map[string]bool)
+	for _, unit := range units {
+		if !unit.MachineTranslated {
+			continue
+		}
+		key := unit.Key
+		if key == "" {
+			key = util.GetTranslationKey(unit.Source)
+		}
+		machineTranslated[key] = true
+	}
+	changed, err := s.writePatchDictionary(patchPath, patch, dictionary, machineTranslated)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		s.logger.Info("Dictionary is unchanged")
+		return nil
+	}
+	s.logger.Success(fmt.Sprintf("Imported %d translations into %s", len(dictionary.Keys), filepath.Base(patchPath)))
+	return nil
+}
+
+// PrefillTranslations machine translates the texts of a game missing from the dictionary of a patch.
+// The translations are added to the dictionary and listed in machine-translated.json so they can be reviewed.
+func (s *TranslationService) PrefillTranslations(ctx context.Context, gameInfo *domain.GameInfo, backend translator.Translator, settings domain.TranslatorSettings, readOriginals func(fn func(relPath string, r io.Reader) error) error) error {
+	patchPath, err := selectPatchPath(ctx, "Select the Patch to pre-fill")
+	if err != nil || patchPath == "" {
+		return err
+	}
+
+	patch, err := s.readPatchDictionary(patchPath)
+	if err != nil {
+		return err
+	}
+	dictionary, err := interchange.ReadDictionary(patch.original)
+	if err != nil {
+		s.logger.Error("Failed to parse dictionary.json")
+		return err
+	}
+	sources, err := s.collectSourceTexts(gameInfo, patch.config, readOriginals)
+	if err != nil {
+		return err
+	}
+
+	untranslated := []domain.TranslationUnit{}
+	for _, unit := range interchange.BuildUnits(dictionary, sources) {
+		if unit.Target == "" {
+			untranslated = append(untranslated, unit)
+		}
+	}
+	if len(untranslated) == 0 {
+		s.logger.Info("Every text is already translated")
+		return nil
+	}
+
+	targetLanguage := patch.config.Locale
+	if targetLanguage == "" {
+		targetLanguage = "en"
+	}
+	batchSize := settings.BatchSize
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+
+	machineTranslated := patch.machineTranslatedKeys()
+	translated := 0
+	for start := 0; start < len(untranslated); start += batchSize {
+		batch := untranslated[start:min(start+batchSize, len(untranslated))]
+		s.logger.Info(fmt.Sprintf("Translating texts %d to %d of %d...", start+1, start+len(batch), len(untranslated)))
+
+		// Escape codes are swapped for placeholders so the backend cannot mangle them
+		texts := make([]string, len(batch))
+		codes := make([][]string, len(batch))
+		for i, unit := range batch {
+			texts[i], codes[i] = translator.ProtectEscapeCodes(unit.Source)
+		}
+		translations, err := backend.Translate(ctx, texts, settings.SourceLanguage, targetLanguage)
+		if err == nil && len(translations) != len(texts) {
+			err = fmt.Errorf("got %d translations for %d texts", len(translations), len(texts))
+		}
+		if err != nil {
+			s.logger.Error("Translation backend failed: " + err.Error())
+			if translated == 0 {
+				return err
+			}
+			// Keep what was translated so far
+			break
+		}
+
+		for i, unit := range batch {
+			translation, err := translator.RestoreEscapeCodes(translations[i], codes[i])
+			if err != nil {
+				s.logger.Warn(fmt.Sprintf("Skipping %q: %v", util.NoNewline(unit.Source), err))
+				continue
+			}
+			if translation == "" {
+				continue
+			}
+			dictionary.Set(unit.Key, translation)
+			machineTranslated[unit.Key] = true
+			translated++
+		}
+	}
+
+	if _, err := s.writePatchDictionary(patchPath, patch, dictionary, machineTranslated); err != nil {
+		return err
+	}
+	s.logger.Success(fmt.Sprintf("Machine translated %d of %d texts into %s", translated, len(untranslated), filepath.Base(patchPath)))
+	return nil
+}
+
+// patchDictionary holds the raw dictionary of a patch along with what is needed to update it
+type patchDictionary struct {
+	original          []byte // dictionary.json as stored in the patch
+	config            *domain.Config
+	machineTranslated []byte // machine-translated.json, nil when the patch has none
+}
+
+// readPatchDictionary reads the raw dictionary.json, the machine translated keys and the config of a patch
+func (s *TranslationService) readPatchDictionary(patchPath string) (*patchDictionary, error) {
+	r, err := s.patchRepo.Open(patchPath)
+	if err != nil {
+		s.logger.Error("Failed to open patch")
+		return nil, err
+	}
+	defer r.Close()
+
+	patch := &patchDictionary{}
+	patch.config, err = s.patchRepo.ReadConfig(r)
+	if err != nil {
+		s.logger.Error("Failed to read config.json")
+		return nil, err
+	}
+	patch.original, err = s.patchRepo.ReadFileFromZip(r, "dictionary.json")
+	if err != nil {
+		s.logger.Error("Failed to read dictionary.json")
+		return nil, err
+	}
+	// Only patches with machine translations have the file
+	patch.machineTranslated, _ = s.patchRepo.ReadFileFromZip(r, machineTranslatedName)
+	return patch, nil
+}
+
+// machineTranslatedKeys returns the keys of the dictionary marked as machine translated
+func (p *patchDictionary) machineTranslatedKeys() map[string]bool {
+	keys := make(map[string]bool)
+	var list []string
+	if p.machineTranslated != nil && json.Unmarshal(p.machineTranslated, &list) == nil {
+		for _, key := range list {
+			keys[key] = true
+		}
+	}
+	return keys
+}
+
+// writePatchDictionary writes the dictionary and machine translated keys of a patch, skipping unchanged files
+func (s *TranslationService) writePatchDictionary(patchPath string, patch *patchDictionary, dictionary *util.OrderedMap, machineTranslated map[string]bool) (bool, error) {
+	encoded, err := interchange.EncodeDictionary(dictionary, patch.original)
+	if err != nil {
+		return false, err
+	}
+
+	// Keep the machine translated keys in dictionary order
+	keys := []string{}
+	for _, key := range dictionary.Keys {
+		if machineTranslated[key] {
+			keys = append(keys, key)
+		}
+	}
+	var encodedKeys []byte
+	if len(keys) > 0 || patch.machineTranslated != nil {
+		if encodedKeys, err = json.MarshalIndent(keys, "", "  "); err != nil {
+			return false, err
+		}
+	}
+
+	changed := false
+	if !bytes.Equal(encoded, patch.original) {
+		if err := s.patchRepo.WriteFileToZip(patchPath, "dictionary.json", encoded); err != nil {
+			s.logger.Error("Failed to write dictionary.json")
+			return false, err
+		}
+		changed = true
+	}
+	if encodedKeys != nil && !bytes.Equal(encodedKeys, patch.machineTranslated) {
+		if err := s.patchRepo.WriteFileToZip(patchPath, machineTranslatedName, encodedKeys); err != nil {
+			s.logger.Error("Failed to write " + machineTranslatedName)
+			return false, err
+		}
+		changed = true
+	}
+	return changed, nil
+}
+
+// collectSourceTexts walks the data files of a game, preferring the original files from the backup
+func (s *TranslationService) collectSourceTexts(gameInfo *domain.GameInfo, config *domain.Config, readOriginals func(fn func(relPath string, r io.Reader) error) error) ([]domain.SourceText, error) {
+	originals := make(map[string][]byte)
+	err := readOriginals(func(relPath string, r io.Reader) error {
+		isData := strings.HasPrefix(relPath, "data/") && strings.HasSuffix(relPath, ".json")
+		isPlugin := (strings.Contains(relPath, "js/plugins/") && strings.HasSuffix(relPath, ".js")) || strings.HasSuffix(relPath, "js/plugins.js")
+		if r == nil || (!isData && !isPlugin) {
+			return nil
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		originals[relPath] = data
+		return nil
+	})
+	if err != nil {
+		s.logger.Warn("No backup found, reading source texts from the current game files")
+	}
+
+	jsonFiles, err := util.ListFilesWithExtension(gameInfo.DataPath, ".json")
+	if err != nil {
+		s.logger.Error("Failed to scan data folder")
+		return nil, err
+	}
+
+	sources := []domain.SourceText{}
+	for _, jsonFile := range jsonFiles {
+		relPath, _ := filepath.Rel(gameInfo.GameDir, jsonFile)
+		data, ok := originals[filepath.ToSlash(relPath)]
+		if !ok {
+			if data, err = os.ReadFile(jsonFile); err != nil {
+				return nil, err
+			}
+		}
+		texts, err := patcher.CollectSourceTexts(jsonFile, data, config)
+		if err != nil {
+			s.logger.Warn("Skipping " + filepath.Base(jsonFile) + ": " + err.Error())
+			continue
+		}
+		sources = append(sources, texts...)
+	}
+
+	pluginsJsPath := filepath.Join(gameInfo.JsPath, "plugins.js")
+	relPath, _ := filepath.Rel(gameInfo.GameDir, pluginsJsPath)
+	pluginsJs, ok := originals[filepath.ToSlash(relPath)]
+	if !ok {
+		pluginsJs, _ = os.ReadFile(pluginsJsPath)
+	}
+	texts, err := patcher.CollectPluginParameterTexts(pluginsJs, config.PluginsToPatch)
+	if err != nil {
+		s.logger.Warn("Skipping plugins.js: " + err.Error())
+	}
+	sources = append(sources, texts...)
+
+	for _, pluginToPatch := range config.PluginsToPatch {
+		if !pluginToPatch.TranslateStrings {
+			continue
+		}
+		pluginPath := patcher.PluginJsPath(gameInfo.JsPath, pluginToPatch.Plugin)
+		relPath, _ := filepath.Rel(gameInfo.GameDir, pluginPath)
+		data, ok := originals[filepath.ToSlash(relPath)]
+		if !ok {
+			if data, err = os.ReadFile(pluginPath); err != nil {
+				s.logger.Warn("Skipping plugin " + pluginToPatch.Plugin + ": " + err.Error())
+				continue
+			}
+		}
+		texts, err := patcher.CollectPluginSourceTexts(pluginToPatch.Plugin, data)
+		if err != nil {
+			s.logger.Warn("Skipping plugin " + pluginToPatch.Plugin + ": " + err.Error())
+			continue
+		}
+		sources = append(sources, texts...)
+	}
+	return sources, nil
+}
+
+// selectPatchPath opens a file dialog to select a patch file
+func selectPatchPath(ctx context.Context, title string) (string, error) {
+	return runtime.OpenFileDialog(ctx, runtime.OpenDialogOptions{
+		Title: title,
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "Patch file",
+				Pattern:     "*.htpatch",
+			},
+		},
+	})
+}
@@ -0,0 +1,58 @@
+package translator
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// escapeCodeRegex matches RPG Maker escape codes such as \C[2], \N[1], \V[3], \G and \{
+var escapeCodeRegex = regexp.MustCompile(`\\(?:[A-Za-z]+(?:\[[^\]]*\])?|[{}.|!<>^$\\])`)
+
+// placeholderRegex matches the placeholders escape codes are replaced with
+var placeholderRegex = regexp.MustCompile(`\{\{(\d+)\}\}`)
+
+// protectedRegex matches escape codes and text that looks like a placeholder already,
+// which is protected the same way so that restoring cannot confuse it with a real placeholder
+var protectedRegex = regexp.MustCompile(escapeCodeRegex.String() + `|` + placeholderRegex.String())
+
+// ProtectEscapeCodes replaces escape codes with numbered placeholders the translation backend leaves alone
+func ProtectEscapeCodes(text string) (string, []string) {
+	codes := []string{}
+	protected := protectedRegex.ReplaceAllStringFunc(text, func(code string) string {
+		codes = append(codes, code)
+		return fmt.Sprintf("{{%d}}", len(codes)-1)
+	})
+	return protected, codes
+}
+
+// RestoreEscapeCodes puts the escape codes back in place of their placeholders.
+// It fails if the backend dropped or invented a placeholder.
+func RestoreEscapeCodes(text string, codes []string) (string, error) {
+	seen := make([]bool, len(codes))
+	var restoreErr error
+	restored := placeholderRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		index, _ := strconv.Atoi(placeholderRegex.FindStringSubmatch(placeholder)[1])
+		if index >= len(codes) {
+			restoreErr = fmt.Errorf("unknown placeholder %s", placeholder)
+			return placeholder
+		}
+		seen[index] = true
+		return codes[index]
+	})
+	if restoreErr != nil {
+		return "", restoreErr
+	}
+
+	missing := []string{}
+	for i, ok := range seen {
+		if !ok {
+			missing = append(missing, codes[i])
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("escape codes lost in translation: %s", strings.Join(missing, " "))
+	}
+	return restored, nil
+}
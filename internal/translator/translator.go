@@ -0,0 +1,93 @@
+// Package translator provides machine translation backends used to pre-fill patch dictionaries
+package translator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Translator translates batches of texts
+type Translator interface {
+	// Translate returns one translation per text, in the same order
+	Translate(ctx context.Context, texts []string, sourceLanguage string, targetLanguage string) ([]string, error)
+}
+
+// TranslateRequest is the body sent to an HTTP translation backend
+type TranslateRequest struct {
+	SourceLanguage string   `json:"sourceLanguage"`
+	TargetLanguage string   `json:"targetLanguage"`
+	Texts          []string `json:"texts"`
+}
+
+// TranslateResponse is the body expected from an HTTP translation backend
+type TranslateResponse struct {
+	Translations []string `json:"translations"`
+}
+
+// HTTPTranslator talks to a translation backend over a generic JSON protocol.
+// It POSTs a TranslateRequest to the endpoint and expects a TranslateResponse back.
+type HTTPTranslator struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+// NewHTTPTranslator creates a translator for the given endpoint.
+// The API key is sent as a bearer token when it is not empty.
+func NewHTTPTranslator(endpoint string, apiKey string) *HTTPTranslator {
+	return &HTTPTranslator{
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Translate sends a batch of texts to the backend
+func (t *HTTPTranslator) Translate(ctx context.Context, texts []string, sourceLanguage string, targetLanguage string) ([]string, error) {
+	body, err := json.Marshal(TranslateRequest{
+		SourceLanguage: sourceLanguage,
+		TargetLanguage: targetLanguage,
+		Texts:          texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if t.apiKey != "" {
+		request.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	response, err := t.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("translation backend returned %s", response.Status)
+	}
+
+	var translateResponse TranslateResponse
+	if err := json.Unmarshal(responseBody, &translateResponse); err != nil {
+		return nil, err
+	}
+	if len(translateResponse.Translations) != len(texts) {
+		return nil, errors.New("translation backend returned a different number of translations")
+	}
+	return translateResponse.Translations, nil
+}
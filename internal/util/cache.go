@@ -23,6 +23,17 @@ func GetUpdateCacheDir() (string, error) {
 	return updateCacheDir, nil
 }
 
+// GetBackupRootDir returns the default folder for backup archives
+// Windows: C:\Users\<user>\AppData\Roaming\htpatcher\backups
+func GetBackupRootDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, "htpatcher", "backups"), nil
+}
+
 // GetUpdateExePath returns the full path to the downloaded update executable
 func GetUpdateExePath() (string, error) {
 	cacheDir, err := GetUpdateCacheDir()
@@ -0,0 +1,129 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// Delta format: the magic header followed by a sequence of operations.
+// A copy operation is deltaOpCopy, the source offset and the length as uvarints.
+// An insert operation is deltaOpInsert, the length as uvarint and the literal bytes.
+var deltaMagic = []byte("HTD1")
+
+const (
+	deltaOpCopy   = 0
+	deltaOpInsert = 1
+
+	// deltaBlockSize is the size of the source blocks used to find matches
+	deltaBlockSize = 16
+)
+
+// Diff computes a binary delta that turns src into dst
+func Diff(src []byte, dst []byte) []byte {
+	// Index the source by aligned blocks, keeping the first occurrence of each block
+	index := make(map[string]int, len(src)/deltaBlockSize)
+	for offset := 0; offset+deltaBlockSize <= len(src); offset += deltaBlockSize {
+		block := string(src[offset : offset+deltaBlockSize])
+		if _, exists := index[block]; !exists {
+			index[block] = offset
+		}
+	}
+
+	var out bytes.Buffer
+	out.Write(deltaMagic)
+
+	literalStart := 0
+	pos := 0
+	for pos+deltaBlockSize <= len(dst) {
+		srcOffset, ok := index[string(dst[pos:pos+deltaBlockSize])]
+		if !ok {
+			pos++
+			continue
+		}
+
+		// Extend the match backwards into the pending literal and forwards past the block
+		start := pos
+		srcStart := srcOffset
+		for start > literalStart && srcStart > 0 && dst[start-1] == src[srcStart-1] {
+			start--
+			srcStart--
+		}
+		end := pos + deltaBlockSize
+		srcEnd := srcOffset + deltaBlockSize
+		for end < len(dst) && srcEnd < len(src) && dst[end] == src[srcEnd] {
+			end++
+			srcEnd++
+		}
+
+		writeDeltaInsert(&out, dst[literalStart:start])
+		writeDeltaCopy(&out, srcStart, end-start)
+		pos = end
+		literalStart = end
+	}
+	writeDeltaInsert(&out, dst[literalStart:])
+
+	return out.Bytes()
+}
+
+// ApplyDelta applies a binary delta created by Diff to src
+func ApplyDelta(src []byte, delta []byte) ([]byte, error) {
+	if !bytes.HasPrefix(delta, deltaMagic) {
+		return nil, errors.New("invalid delta header")
+	}
+	reader := bytes.NewReader(delta[len(deltaMagic):])
+
+	var out bytes.Buffer
+	for reader.Len() > 0 {
+		op, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case deltaOpCopy:
+			offset, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return nil, err
+			}
+			length, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return nil, err
+			}
+			if offset > uint64(len(src)) || length > uint64(len(src))-offset {
+				return nil, errors.New("delta copy out of range")
+			}
+			out.Write(src[offset : offset+length])
+		case deltaOpInsert:
+			length, err := binary.ReadUvarint(reader)
+			if err != nil {
+				return nil, err
+			}
+			if length > uint64(reader.Len()) {
+				return nil, errors.New("delta insert out of range")
+			}
+			literal := make([]byte, length)
+			if _, err := reader.Read(literal); err != nil {
+				return nil, err
+			}
+			out.Write(literal)
+		default:
+			return nil, errors.New("invalid delta operation")
+		}
+	}
+	return out.Bytes(), nil
+}
+
+func writeDeltaCopy(out *bytes.Buffer, offset int, length int) {
+	out.WriteByte(deltaOpCopy)
+	out.Write(binary.AppendUvarint(nil, uint64(offset)))
+	out.Write(binary.AppendUvarint(nil, uint64(length)))
+}
+
+func writeDeltaInsert(out *bytes.Buffer, literal []byte) {
+	if len(literal) == 0 {
+		return
+	}
+	out.WriteByte(deltaOpInsert)
+	out.Write(binary.AppendUvarint(nil, uint64(len(literal))))
+	out.Write(literal)
+}
@@ -0,0 +1,91 @@
+package util
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestDeltaRoundTrip(t *testing.T) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20)
+	tests := []struct {
+		name string
+		src  string
+		dst  string
+	}{
+		{"empty", "", ""},
+		{"empty source", "", "new file"},
+		{"empty target", text, ""},
+		{"identical", text, text},
+		{"edited middle", text, strings.Replace(text, "lazy", "sleepy", 3)},
+		{"prepended and appended", text, "header\n" + text + "\nfooter"},
+		{"shorter than a block", "short", "shorter"},
+		{"reordered", text[:400] + text[400:], text[400:] + text[:400]},
+		{"binary", "\x00\x01\x02\xff" + text + "\x00", "\xff\x00" + text + "\x01\x02"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delta := Diff([]byte(tt.src), []byte(tt.dst))
+			got, err := ApplyDelta([]byte(tt.src), delta)
+			if err != nil {
+				t.Fatalf("ApplyDelta: %v", err)
+			}
+			if string(got) != tt.dst {
+				t.Errorf("ApplyDelta = %q, want %q", got, tt.dst)
+			}
+		})
+	}
+}
+
+func TestDeltaCopiesUnchangedBlocks(t *testing.T) {
+	src := []byte(strings.Repeat("0123456789abcdef", 64))
+	dst := append(bytes.Clone(src), "tail"...)
+	if delta := Diff(src, dst); len(delta) >= len(dst)/2 {
+		t.Errorf("delta of %d bytes for a %d bytes target sharing its source", len(delta), len(dst))
+	}
+}
+
+func TestApplyDeltaMalformed(t *testing.T) {
+	src := []byte("0123456789")
+	op := func(parts ...uint64) []byte {
+		delta := bytes.Clone(deltaMagic)
+		for _, part := range parts {
+			delta = binary.AppendUvarint(delta, part)
+		}
+		return delta
+	}
+	tests := []struct {
+		name  string
+		delta []byte
+	}{
+		{"empty", nil},
+		{"bad header", []byte("HTD0")},
+		{"unknown operation", op(7)},
+		{"truncated copy offset", op(deltaOpCopy)},
+		{"truncated copy length", op(deltaOpCopy, 2)},
+		{"copy past the end", op(deltaOpCopy, 8, 3)},
+		{"copy offset past the end", op(deltaOpCopy, 11, 0)},
+		{"copy offset overflow", op(deltaOpCopy, ^uint64(0), 2)},
+		{"copy length overflow", op(deltaOpCopy, 2, ^uint64(0))},
+		{"truncated insert length", op(deltaOpInsert)},
+		{"insert past the end", append(op(deltaOpInsert, 5), "abc"...)},
+		{"insert length overflow", append(op(deltaOpInsert, ^uint64(0)), "abc"...)},
+		{"invalid uvarint", append(bytes.Clone(deltaMagic), deltaOpCopy, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ApplyDelta(src, tt.delta); err == nil {
+				t.Error("ApplyDelta accepted a malformed delta")
+			}
+		})
+	}
+}
+
+func FuzzApplyDelta(f *testing.F) {
+	f.Add([]byte("0123456789"), Diff([]byte("0123456789"), []byte("01234x56789")))
+	f.Add([]byte("abc"), append(bytes.Clone(deltaMagic), deltaOpCopy, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 0x02))
+	f.Fuzz(func(t *testing.T, src []byte, delta []byte) {
+		ApplyDelta(src, delta) // Must not panic
+	})
+}
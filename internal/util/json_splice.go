@@ -0,0 +1,333 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+// jsonNode is a value of a JSON document with its position
+type jsonNode struct {
+	Kind     byte // '{', '[', '"', '0' for numbers, or the first letter of true, false and null
+	Start    int
+	End      int
+	Keys     []string
+	Children []*jsonNode
+}
+
+// jsonEdit replaces the bytes of a value
+type jsonEdit struct {
+	start       int
+	end         int
+	replacement []byte
+}
+
+// SpliceJSON returns the original document with only the values that differ in the patched one rewritten.
+// Whitespace, key order, number formatting and the escapes of unchanged strings are kept. Keys the patched
+// document adds with an empty value, as structs do for fields the original lacks, are ignored.
+func SpliceJSON(original []byte, patched []byte) ([]byte, error) {
+	root, err := scanJSONNode(original)
+	if err != nil {
+		return nil, err
+	}
+	value, err := ParseJSON(patched)
+	if err != nil {
+		return nil, err
+	}
+
+	var edits []jsonEdit
+	if err := diffJSONNode(original, root, value, &edits); err != nil {
+		return nil, err
+	}
+	if len(edits) == 0 {
+		return original, nil
+	}
+
+	var result bytes.Buffer
+	last := 0
+	for _, edit := range edits {
+		result.Write(original[last:edit.start])
+		result.Write(edit.replacement)
+		last = edit.end
+	}
+	result.Write(original[last:])
+	return result.Bytes(), nil
+}
+
+// diffJSONNode records the edits turning a value of the original document into the patched value
+func diffJSONNode(original []byte, node *jsonNode, value any, edits *[]jsonEdit) error {
+	if sameJSONShape(node, value) {
+		switch v := value.(type) {
+		case *OrderedMap:
+			// Decoders keep the last of duplicate keys, the earlier ones are left as they are
+			last := make(map[string]int, len(node.Keys))
+			for i, key := range node.Keys {
+				last[key] = i
+			}
+			for i, key := range node.Keys {
+				if last[key] != i {
+					continue
+				}
+				if err := diffJSONNode(original, node.Children[i], v.Values[key], edits); err != nil {
+					return err
+				}
+			}
+			return nil
+		case []any:
+			for i, item := range v {
+				if err := diffJSONNode(original, node.Children[i], item, edits); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if jsonScalarEqual(original, node, value) {
+			return nil
+		}
+	}
+
+	replacement, err := MarshalUnescaped(value)
+	if err != nil {
+		return err
+	}
+	*edits = append(*edits, jsonEdit{start: node.Start, end: node.End, replacement: replacement})
+	return nil
+}
+
+// sameJSONShape tells whether a patched value has the type of the original node and,
+// for objects and arrays, the same keys or length so that its children can be compared one by one
+func sameJSONShape(node *jsonNode, value any) bool {
+	switch v := value.(type) {
+	case *OrderedMap:
+		if node.Kind != '{' {
+			return false
+		}
+		for _, key := range node.Keys {
+			if _, ok := v.Values[key]; !ok {
+				return false
+			}
+		}
+		for _, key := range v.Keys {
+			if !slices.Contains(node.Keys, key) && !isEmptyJSON(v.Values[key]) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		return node.Kind == '[' && len(node.Children) == len(v)
+	case string:
+		return node.Kind == '"'
+	case float64:
+		return node.Kind == '0'
+	case bool:
+		return node.Kind == 't' || node.Kind == 'f'
+	case nil:
+		return node.Kind == 'n'
+	}
+	return false
+}
+
+// jsonScalarEqual tells whether a string, number or literal node holds the patched value
+func jsonScalarEqual(original []byte, node *jsonNode, value any) bool {
+	raw := original[node.Start:node.End]
+	switch v := value.(type) {
+	case string:
+		var s string
+		return json.Unmarshal(raw, &s) == nil && s == v
+	case float64:
+		f, err := strconv.ParseFloat(string(raw), 64)
+		return err == nil && f == v
+	case bool:
+		return (node.Kind == 't') == v
+	case nil:
+		return true
+	}
+	return false
+}
+
+// isEmptyJSON tells whether a value is the zero value of its type, recursively for objects
+func isEmptyJSON(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case []any:
+		return len(v) == 0
+	case *OrderedMap:
+		for _, key := range v.Keys {
+			if !isEmptyJSON(v.Values[key]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// scanJSONNode scans a JSON document into nodes with their positions
+func scanJSONNode(data []byte) (*jsonNode, error) {
+	s := &jsonScanner{data: data}
+	node, err := s.value(0)
+	if err != nil {
+		return nil, err
+	}
+	s.skipSpace()
+	if s.pos != len(data) {
+		return nil, fmt.Errorf("unexpected data after JSON value at offset %d", s.pos)
+	}
+	return node, nil
+}
+
+// jsonMaxDepth limits the nesting of scanned documents
+const jsonMaxDepth = 10000
+
+type jsonScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *jsonScanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (s *jsonScanner) errorf(format string, args ...any) error {
+	return fmt.Errorf("offset %d: %s", s.pos, fmt.Sprintf(format, args...))
+}
+
+func (s *jsonScanner) value(depth int) (*jsonNode, error) {
+	if depth > jsonMaxDepth {
+		return nil, s.errorf("too deeply nested")
+	}
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		return nil, s.errorf("unexpected end of JSON")
+	}
+
+	node := &jsonNode{Start: s.pos}
+	switch c := s.data[s.pos]; {
+	case c == '{':
+		node.Kind = '{'
+		s.pos++
+		s.skipSpace()
+		if s.pos < len(s.data) && s.data[s.pos] == '}' {
+			s.pos++
+			break
+		}
+		for {
+			s.skipSpace()
+			keyNode, err := s.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			if keyNode.Kind != '"' {
+				return nil, s.errorf("expected an object key")
+			}
+			var key string
+			if err := json.Unmarshal(s.data[keyNode.Start:keyNode.End], &key); err != nil {
+				return nil, s.errorf("invalid object key")
+			}
+			s.skipSpace()
+			if s.pos >= len(s.data) || s.data[s.pos] != ':' {
+				return nil, s.errorf("expected ':'")
+			}
+			s.pos++
+			child, err := s.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			node.Keys = append(node.Keys, key)
+			node.Children = append(node.Children, child)
+			done, err := s.endOfList('}')
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				break
+			}
+		}
+	case c == '[':
+		node.Kind = '['
+		s.pos++
+		s.skipSpace()
+		if s.pos < len(s.data) && s.data[s.pos] == ']' {
+			s.pos++
+			break
+		}
+		for {
+			child, err := s.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			node.Children = append(node.Children, child)
+			done, err := s.endOfList(']')
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				break
+			}
+		}
+	case c == '"':
+		node.Kind = '"'
+		for s.pos++; s.pos < len(s.data) && s.data[s.pos] != '"'; s.pos++ {
+			if s.data[s.pos] == '\\' {
+				s.pos++
+			}
+		}
+		if s.pos >= len(s.data) {
+			return nil, s.errorf("unterminated string")
+		}
+		s.pos++
+	case c == '-' || (c >= '0' && c <= '9'):
+		node.Kind = '0'
+		for s.pos < len(s.data) && bytes.IndexByte([]byte("+-0123456789.eE"), s.data[s.pos]) >= 0 {
+			s.pos++
+		}
+	case c == 't' || c == 'f' || c == 'n':
+		node.Kind = c
+		for _, literal := range []string{"true", "false", "null"} {
+			if bytes.HasPrefix(s.data[s.pos:], []byte(literal)) {
+				s.pos += len(literal)
+				break
+			}
+		}
+		if s.pos == node.Start {
+			return nil, s.errorf("invalid literal")
+		}
+	default:
+		return nil, s.errorf("unexpected character %q", c)
+	}
+	node.End = s.pos
+	return node, nil
+}
+
+// endOfList consumes the comma between two items, or the closing delimiter and tells the list ended
+func (s *jsonScanner) endOfList(closing byte) (bool, error) {
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		return false, s.errorf("unexpected end of JSON")
+	}
+	switch s.data[s.pos] {
+	case ',':
+		s.pos++
+		return false, nil
+	case closing:
+		s.pos++
+		return true, nil
+	}
+	return false, s.errorf("expected ',' or %q", closing)
+}
@@ -0,0 +1,167 @@
+package util
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSpliceJSONUnchanged(t *testing.T) {
+	tests := []string{
+		`{}`,
+		`[]`,
+		`[null,{"id":1,"name":"薬草","note":"<tag>\n&","price":1.50,"rate":1e2,"neg":-0}]`,
+		"[\r\nnull,\r\n{\"id\":1, \"list\" : [ ],\r\n \"flag\":true}\r\n]",
+		`{"a":1,"a":2,"b":{"c":"x","c":"y"}}`,
+		`  "padded string"  `,
+	}
+	for _, original := range tests {
+		value, err := ParseJSON([]byte(original))
+		if err != nil {
+			t.Fatalf("ParseJSON(%q): %v", original, err)
+		}
+		patched, err := MarshalUnescaped(value)
+		if err != nil {
+			t.Fatalf("MarshalUnescaped(%q): %v", original, err)
+		}
+		got, err := SpliceJSON([]byte(original), patched)
+		if err != nil {
+			t.Fatalf("SpliceJSON(%q): %v", original, err)
+		}
+		if string(got) != original {
+			t.Errorf("SpliceJSON changed unchanged input\noriginal: %q\ngot:      %q", original, got)
+		}
+	}
+}
+
+func TestSpliceJSONChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		patched  string
+		want     string
+	}{
+		{
+			name:     "changed string keeps escapes of the others",
+			original: `{"name":"薬","note":"メモ"}`,
+			patched:  `{"name":"Herb","note":"メモ"}`,
+			want:     `{"name":"Herb","note":"メモ"}`,
+		},
+		{
+			name:     "CRLF whitespace is kept",
+			original: "[\r\n{\"name\":\"a\"},\r\n{\"name\":\"b\"}\r\n]",
+			patched:  `[{"name":"a"},{"name":"B"}]`,
+			want:     "[\r\n{\"name\":\"a\"},\r\n{\"name\":\"B\"}\r\n]",
+		},
+		{
+			name:     "numbers equal in value keep their format",
+			original: `{"price":1.50,"count":10,"name":"x"}`,
+			patched:  `{"price":1.5,"count":10,"name":"y"}`,
+			want:     `{"price":1.50,"count":10,"name":"y"}`,
+		},
+		{
+			name:     "changed number",
+			original: `{"price":1.50}`,
+			patched:  `{"price":2}`,
+			want:     `{"price":2}`,
+		},
+		{
+			name:     "added keys with a zero value are ignored",
+			original: `{"name":"a"}`,
+			patched:  `{"name":"b","note":"","list":[],"count":0,"flag":false,"extra":null,"nested":{"x":""}}`,
+			want:     `{"name":"b"}`,
+		},
+		{
+			name:     "added key with a value rewrites the object",
+			original: `{"name":"a"}`,
+			patched:  `{"name":"a","note":"n"}`,
+			want:     `{"name":"a","note":"n"}`,
+		},
+		{
+			name:     "duplicate keys change the last occurrence",
+			original: `{"a":"x","a":"y"}`,
+			patched:  `{"a":"z"}`,
+			want:     `{"a":"x","a":"z"}`,
+		},
+		{
+			name:     "array with another length is rewritten",
+			original: `{"list": [1, 2]}`,
+			patched:  `{"list":[1,2,3]}`,
+			want:     `{"list": [1,2,3]}`,
+		},
+		{
+			name:     "type change",
+			original: `{"value": "1"}`,
+			patched:  `{"value":1}`,
+			want:     `{"value": 1}`,
+		},
+		{
+			name:     "HTML characters stay unescaped",
+			original: `{"text":"a"}`,
+			patched:  `{"text":"<b>&</b>"}`,
+			want:     `{"text":"<b>&</b>"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SpliceJSON([]byte(tt.original), []byte(tt.patched))
+			if err != nil {
+				t.Fatalf("SpliceJSON: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("SpliceJSON = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpliceJSONInvalid(t *testing.T) {
+	tests := []struct {
+		original string
+		patched  string
+	}{
+		{`{"a":1`, `{"a":1}`},
+		{`{"a":1} x`, `{"a":1}`},
+		{`{"a" 1}`, `{"a":1}`},
+		{`[1,]`, `[1]`},
+		{`{"a":1}`, `{"a":`},
+	}
+	for _, tt := range tests {
+		if _, err := SpliceJSON([]byte(tt.original), []byte(tt.patched)); err == nil {
+			t.Errorf("SpliceJSON(%q, %q) accepted invalid JSON", tt.original, tt.patched)
+		}
+	}
+}
+
+// FuzzSpliceJSON checks that splicing a document with its own decoded value gives back the same bytes
+func FuzzSpliceJSON(f *testing.F) {
+	for _, seed := range []string{
+		`{}`,
+		`[null,{"id":1,"name":"薬","price":1.50}]`,
+		"[\r\n{\"a\" : [ ] }\r\n]",
+		`{"a":1,"a":{"b":2}}`,
+		`"😀"`,
+		`-0.0e+1`,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if !json.Valid(data) {
+			return
+		}
+		value, err := ParseJSON(data)
+		if err != nil {
+			return
+		}
+		patched, err := MarshalUnescaped(value)
+		if err != nil {
+			return
+		}
+		got, err := SpliceJSON(data, patched)
+		if err != nil {
+			t.Fatalf("SpliceJSON(%q): %v", data, err)
+		}
+		if string(got) != string(data) {
+			t.Fatalf("SpliceJSON changed unchanged input\noriginal: %q\ngot:      %q", data, got)
+		}
+	})
+}
@@ -3,7 +3,9 @@ package util
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 )
 
 // OrderedMap is a map that preserves insertion order of keys.
@@ -46,15 +48,15 @@ func (o *OrderedMap) MarshalJSON() ([]byte, error) {
 		}
 
 		// Marshal key
-		keyBytes, err := json.Marshal(key)
+		keyBytes, err := MarshalUnescaped(key)
 		if err != nil {
 			return nil, err
 		}
 		buf.Write(keyBytes)
 		buf.WriteString(":")
 
-		// Marshal value (recursively handles nested *OrderedMap via json.Marshal)
-		valBytes, err := json.Marshal(o.Values[key])
+		// Marshal value (recursively handles nested *OrderedMap)
+		valBytes, err := MarshalUnescaped(o.Values[key])
 		if err != nil {
 			return nil, err
 		}
@@ -65,8 +67,24 @@ func (o *OrderedMap) MarshalJSON() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// MarshalUnescaped encodes a value as JSON without escaping <, > and &.
+// Callers that marshal an OrderedMap with json.Marshal get them escaped again.
+func MarshalUnescaped(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
 // UnmarshalJSON deserializes JSON into OrderedMap while preserving key order.
 func (o *OrderedMap) UnmarshalJSON(data []byte) error {
+	// null leaves the map unchanged, as encoding/json does for its own types
+	if string(bytes.TrimSpace(data)) == "null" {
+		return nil
+	}
 	o.Keys = []string{}
 	o.Values = make(map[string]any)
 
@@ -99,8 +117,7 @@ func (o *OrderedMap) UnmarshalJSON(data []byte) error {
 			return err
 		}
 
-		o.Keys = append(o.Keys, key)
-		o.Values[key] = value
+		o.Set(key, value)
 	}
 
 	// Read closing brace
@@ -115,6 +132,19 @@ func (o *OrderedMap) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// ParseJSON parses a single JSON value, decoding objects to *OrderedMap and arrays to []any.
+func ParseJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	value, err := parseJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unexpected data after JSON value")
+	}
+	return value, nil
+}
+
 // parseJSONValue parses the next JSON value from the decoder, preserving object key order.
 func parseJSONValue(dec *json.Decoder) (any, error) {
 	token, err := dec.Token()
@@ -145,8 +175,7 @@ func parseJSONValue(dec *json.Decoder) (any, error) {
 					return nil, err
 				}
 
-				om.Keys = append(om.Keys, key)
-				om.Values[key] = value
+				om.Set(key, value)
 			}
 			// Consume closing brace
 			if _, err := dec.Token(); err != nil {
@@ -155,8 +184,8 @@ func parseJSONValue(dec *json.Decoder) (any, error) {
 			return om, nil
 
 		case '[':
-			// Parse array
-			var arr []any
+			// Parse array, empty ones included so they are encoded back as []
+			arr := []any{}
 			for dec.More() {
 				value, err := parseJSONValue(dec)
 				if err != nil {
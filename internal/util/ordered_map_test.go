@@ -0,0 +1,64 @@
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// FuzzOrderedMap checks that objects decode to the values encoding/json finds, each key once in the order
+// it first appears, and that encoding a decoded object and decoding it again gives the same object
+func FuzzOrderedMap(f *testing.F) {
+	for _, seed := range []string{
+		`{}`,
+		`{"b":1,"a":2}`,
+		`{"a":1,"a":2,"b":3}`,
+		`{"list":[],"nested":{"z":null,"y":[{"x":true}]},"text":"薬\n<br>&"}`,
+		`{"n":1.0,"e":1e21,"neg":-0.5}`,
+		` { "spaced" : [ 1 , 2 ] } `,
+	} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var expected map[string]any
+		if json.Unmarshal(data, &expected) != nil || expected == nil {
+			return
+		}
+		om := NewOrderedMap()
+		if err := json.Unmarshal(data, om); err != nil {
+			t.Fatalf("unmarshal %q: %v", data, err)
+		}
+		if len(om.Keys) != len(om.Values) {
+			t.Fatalf("unmarshal %q: %d keys for %d values", data, len(om.Keys), len(om.Values))
+		}
+
+		encoded, err := json.Marshal(om)
+		if err != nil {
+			t.Fatalf("marshal %q: %v", data, err)
+		}
+		var actual map[string]any
+		if err := json.Unmarshal(encoded, &actual); err != nil {
+			t.Fatalf("marshal %q gave invalid JSON %q: %v", data, encoded, err)
+		}
+		if !reflect.DeepEqual(expected, actual) {
+			t.Fatalf("round trip of %q changed the values: %q", data, encoded)
+		}
+
+		again := NewOrderedMap()
+		if err := json.Unmarshal(encoded, again); err != nil {
+			t.Fatalf("unmarshal %q: %v", encoded, err)
+		}
+		if !slices.Equal(om.Keys, again.Keys) {
+			t.Fatalf("round trip of %q changed the key order: %v to %v", data, om.Keys, again.Keys)
+		}
+		reencoded, err := MarshalUnescaped(again)
+		if err != nil {
+			t.Fatalf("marshal %q: %v", encoded, err)
+		}
+		var unescaped map[string]any
+		if err := json.Unmarshal(reencoded, &unescaped); err != nil || !reflect.DeepEqual(expected, unescaped) {
+			t.Fatalf("unescaped encoding of %q changed the values: %q", data, reencoded)
+		}
+	})
+}
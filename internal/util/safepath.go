@@ -0,0 +1,78 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ValidateRelativePath checks that a slash separated path stays inside the folder it is relative to.
+// Absolute paths, drive letters, backslashes and ".." components are rejected.
+func ValidateRelativePath(relPath string) error {
+	switch {
+	case relPath == "":
+		return fmt.Errorf("empty path")
+	case strings.ContainsAny(relPath, "\\:\x00"):
+		return fmt.Errorf("%q contains a backslash, colon or NUL character", relPath)
+	case path.IsAbs(relPath) || filepath.IsAbs(relPath):
+		return fmt.Errorf("%q is an absolute path", relPath)
+	}
+	for _, part := range strings.Split(relPath, "/") {
+		if part == ".." {
+			return fmt.Errorf("%q escapes its folder", relPath)
+		}
+		if part == "" || part == "." {
+			return fmt.Errorf("%q is not a clean path", relPath)
+		}
+	}
+	return nil
+}
+
+// SafeJoin joins a slash separated relative path to a root folder, making sure the result stays inside it.
+// Existing symlinks along the way are rejected, since they could point outside of the root.
+func SafeJoin(root string, relPath string) (string, error) {
+	if err := ValidateRelativePath(relPath); err != nil {
+		return "", err
+	}
+
+	current := root
+	for _, part := range strings.Split(relPath, "/") {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			// The rest of the path is created when writing
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("%q goes through the symlink %s", relPath, current)
+		}
+	}
+
+	joined := filepath.Join(root, filepath.FromSlash(relPath))
+	rel, err := filepath.Rel(root, joined)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%q escapes its folder", relPath)
+	}
+	return joined, nil
+}
+
+// IsInSubtree reports whether a slash separated relative path is one of the subtrees or inside one.
+// Paths are compared case insensitively, as on Windows.
+func IsInSubtree(relPath string, subtrees []string) bool {
+	for _, subtree := range subtrees {
+		subtree = strings.Trim(strings.ReplaceAll(subtree, "\\", "/"), "/")
+		if subtree == "" {
+			return true
+		}
+		if strings.EqualFold(relPath, subtree) ||
+			(len(relPath) > len(subtree) && strings.EqualFold(relPath[:len(subtree)], subtree) && relPath[len(subtree)] == '/') {
+			return true
+		}
+	}
+	return false
+}
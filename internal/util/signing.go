@@ -0,0 +1,53 @@
+package util
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+// GenerateSigningKey generates a base64 encoded ed25519 key pair
+func GenerateSigningKey() (string, string, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(publicKey), base64.StdEncoding.EncodeToString(privateKey), nil
+}
+
+// Sign signs data with a base64 encoded ed25519 private key and returns the base64 encoded signature
+func Sign(privateKey string, data []byte) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(privateKey)
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return "", errors.New("invalid private key")
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(ed25519.PrivateKey(key), data)), nil
+}
+
+// VerifySignature checks a base64 encoded signature of data against a base64 encoded ed25519 public key
+func VerifySignature(publicKey string, data []byte, signature string) bool {
+	key, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(key, data, sig)
+}
+
+// ParsePublicKey decodes a base64 encoded ed25519 public key
+func ParsePublicKey(publicKey string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// KeyFingerprint returns a short fingerprint of a base64 encoded public key for display
+func KeyFingerprint(publicKey string) string {
+	return HashBytes([]byte(publicKey))[:16]
+}